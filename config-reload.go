@@ -0,0 +1,142 @@
+package main
+
+import (
+	analogdiscovery "digitrans-lab-go/internal/analog-discovery"
+	"digitrans-lab-go/internal/config"
+	currentsession "digitrans-lab-go/internal/current-session"
+	"digitrans-lab-go/internal/peripherals"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errRestartRequired = errors.New("changes need a restart")
+	errSessionActive   = errors.New("changes can't be applied during a session")
+)
+
+// reopenRule reopens a peripheral when a field under prefix changed
+type reopenRule struct {
+	prefix     string
+	peripheral peripherals.Entry
+}
+
+// ReloadReport is what a reload applied
+type ReloadReport struct {
+	Applied  []config.Change `json:"applied"`
+	Reopened []string        `json:"reopened"`
+	// Peripherals that didn't come back after reopening. They are retried in the background.
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// configReloader re-reads the config file and applies what can change without a restart
+type configReloader struct {
+	mu   sync.Mutex
+	live *config.Live
+	// apply pushes settings that are copied out of the config into the packages holding them
+	apply  func(cfg *config.Config)
+	reopen []reopenRule
+}
+
+// Reload loads the config file again and applies it. Nothing is applied when the
+// file is invalid, when a restart-only field changed, or when a field that
+// reopens hardware changed while a session is active.
+func (r *configReloader) Reload() (ReloadReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Load(r.live.Path())
+	if err != nil {
+		return ReloadReport{}, err
+	}
+
+	changes := config.Diff(r.live.Get(), next)
+	var restart, idle []string
+	for _, change := range changes {
+		switch change.Reload {
+		case config.ReloadRestart:
+			restart = append(restart, change.Field)
+		case config.ReloadIdle:
+			idle = append(idle, change.Field)
+		}
+	}
+	if len(restart) > 0 {
+		return ReloadReport{}, fmt.Errorf("%w: %s", errRestartRequired, strings.Join(restart, ", "))
+	}
+	if len(idle) > 0 && currentsession.GetCurrentSession().IsActive() {
+		return ReloadReport{}, fmt.Errorf("%w: %s", errSessionActive, strings.Join(idle, ", "))
+	}
+
+	report := ReloadReport{Applied: changes, Reopened: []string{}}
+	if len(changes) == 0 {
+		report.Applied = []config.Change{}
+		return report, nil
+	}
+
+	r.live.Set(next)
+	r.apply(next)
+
+	for _, rule := range r.reopen {
+		name := rule.peripheral.Name()
+		if slices.Contains(report.Reopened, name) || !slices.ContainsFunc(changes, func(change config.Change) bool {
+			return strings.HasPrefix(change.Field, rule.prefix)
+		}) {
+			continue
+		}
+		report.Reopened = append(report.Reopened, name)
+		if !rule.peripheral.Reopen() {
+			report.Unavailable = append(report.Unavailable, name)
+		}
+	}
+	return report, nil
+}
+
+// reloadOnSignal reloads the config every time the process gets SIGHUP
+func (r *configReloader) reloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		report, err := r.Reload()
+		if err != nil {
+			log.Printf("Config reload rejected: %v", err)
+			continue
+		}
+		fields := make([]string, len(report.Applied))
+		for i, change := range report.Applied {
+			fields[i] = change.Field
+		}
+		log.Printf("Config reloaded, applied: [%s], reopened: [%s]",
+			strings.Join(fields, ", "), strings.Join(report.Reopened, ", "))
+	}
+}
+
+func handleReloadConfig(r *configReloader) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		report, err := r.Reload()
+		switch {
+		case errors.Is(err, errRestartRequired), errors.Is(err, errSessionActive):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, report)
+		}
+	}
+}
+
+func allowedFromConfig(cfg *config.Config) analogdiscovery.Allowed {
+	return analogdiscovery.Allowed{
+		OutputPins: cfg.AnalogDiscovery.OutputPins,
+		Channels:   cfg.AnalogDiscovery.WavegenChannels,
+		Functions:  cfg.AnalogDiscovery.WavegenFunctions,
+	}
+}
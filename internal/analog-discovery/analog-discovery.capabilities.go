@@ -0,0 +1,64 @@
+package analogdiscovery
+
+import "math"
+
+// Capabilities describes what the instrument offers to clients, so they can
+// build their controls from it
+type Capabilities struct {
+	DeviceID         int32               `json:"deviceId"`
+	DeviceRevision   int32               `json:"deviceRevision"`
+	SerialNumber     string              `json:"serialNumber"`
+	OutputPins       []int               `json:"outputPins"`
+	WavegenChannels  []int               `json:"wavegenChannels"`
+	WavegenFunctions []string            `json:"wavegenFunctions"`
+	LogicAnalyzer    LogicAnalyzerLimits `json:"logicAnalyzer"`
+}
+
+type LogicAnalyzerLimits struct {
+	BufferMax       int `json:"bufferMax"`
+	MinSampleRateHz int `json:"minSampleRateHz"`
+	MaxSampleRateHz int `json:"maxSampleRateHz"`
+}
+
+func (ad *AnalogDiscoveryDevice) Capabilities() (Capabilities, error) {
+	limits, err := ad.getLogicAnalyzerLimits()
+	if err != nil {
+		return Capabilities{}, err
+	}
+	allowed := GetAllowed()
+	return Capabilities{
+		DeviceID:         ad.DeviceID,
+		DeviceRevision:   ad.DeviceRevision,
+		SerialNumber:     ad.SerialNumber,
+		OutputPins:       allowed.OutputPins,
+		WavegenChannels:  allowed.Channels,
+		WavegenFunctions: allowed.Functions,
+		LogicAnalyzer:    limits,
+	}, nil
+}
+
+// The limits are fixed by the hardware, so they are only queried once
+func (ad *AnalogDiscoveryDevice) getLogicAnalyzerLimits() (LogicAnalyzerLimits, error) {
+	ad.mu_logicAnalyzer.Lock()
+	defer ad.mu_logicAnalyzer.Unlock()
+
+	if ad.logicLimits != nil {
+		return *ad.logicLimits, nil
+	}
+
+	bufferMax, err := ad.getDigitalInBufferSizeMax()
+	if err != nil {
+		return LogicAnalyzerLimits{}, err
+	}
+	internalClock, err := ad.getDigitalInClockAndDividerInfo()
+	if err != nil {
+		return LogicAnalyzerLimits{}, err
+	}
+
+	ad.logicLimits = &LogicAnalyzerLimits{
+		BufferMax:       bufferMax,
+		MinSampleRateHz: logicAnalyzerMinSampleRateHz,
+		MaxSampleRateHz: int(math.Floor(internalClock)),
+	}
+	return *ad.logicLimits, nil
+}
@@ -5,42 +5,76 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
 
-var OutputPins = []int{12, 13, 14, 15}
-var outputChannels = []int{0, 1}
-var wavegenFunctions = []string{"sine", "rampup", "triangle", "pulse"}
+// Allowed is what clients may drive on the instrument
+type Allowed struct {
+	OutputPins []int
+	Channels   []int
+	Functions  []string
+}
+
+var (
+	allowedMu sync.RWMutex
+	allowed   Allowed
+)
+
+// SetAllowed replaces the pins, channels and functions clients may use
+func SetAllowed(a Allowed) {
+	allowedMu.Lock()
+	defer allowedMu.Unlock()
+	allowed = a
+}
+
+func GetAllowed() Allowed {
+	allowedMu.RLock()
+	defer allowedMu.RUnlock()
+	return allowed
+}
 
 // check if given pin is allowed
 func isPinAllowed(pin int) bool {
-	for _, allowedPin := range OutputPins {
-		if pin == allowedPin {
-			return true
-		}
-	}
-	return false
+	return slices.Contains(GetAllowed().OutputPins, pin)
 }
 
 // check if given channel is allowed
 func isChannelAllowed(channel int) bool {
-	for _, allowedChannel := range outputChannels {
-		if channel == allowedChannel {
-			return true
-		}
-	}
-	return false
+	return slices.Contains(GetAllowed().Channels, channel)
 }
 
 // check if given function is allowed
 func isFunctionAllowed(function string) bool {
-	for _, allowedFunction := range wavegenFunctions {
-		if function == allowedFunction {
-			return true
-		}
+	return slices.Contains(GetAllowed().Functions, function)
+}
+
+// ErrorStatus is the HTTP status the instrument's errors are answered with
+func ErrorStatus(err error) int {
+	var validationErr ValidationError
+	var deviceUnavailableErr DeviceUnavailableError
+	var deviceRuntimeErr DeviceRuntimeError
+
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrLogicCaptureBusy):
+		return http.StatusConflict
+	case errors.As(err, &deviceUnavailableErr), errors.As(err, &deviceRuntimeErr):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
 	}
-	return false
+}
+
+func respond(c *gin.Context, response any, err error) {
+	if err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // handler for oscilloscope feature
@@ -49,6 +83,20 @@ type GetScopeDataRequest struct {
 	IsFirstCapture int `json:"isFirstCapture"`
 }
 
+// GetScopeData reads a capture of a scope channel. Only the first capture
+// comes with the sample times.
+func GetScopeData(device *AnalogDiscoveryDevice, scopeReq GetScopeDataRequest) (gin.H, error) {
+	voltages, times, _ := device.ReadScopeValues(scopeReq.Channel, scopeReq.IsFirstCapture)
+	if len(voltages) <= 0 || len(times) <= 0 {
+		return nil, ValidationError{Message: "No scope values"}
+	}
+
+	if scopeReq.IsFirstCapture == 0 {
+		return gin.H{"channel": scopeReq.Channel, "voltages": voltages, "times": 0}, nil
+	}
+	return gin.H{"channel": scopeReq.Channel, "voltages": voltages, "times": times}, nil
+}
+
 func HandleScopeGetData(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 
@@ -59,18 +107,8 @@ func HandleScopeGetData(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 			return
 		}
 
-		voltages, times, _ := device.ReadScopeValues(scopeReq.Channel, scopeReq.IsFirstCapture)
-		if len(voltages) <= 0 || len(times) <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "No scope values"})
-			return
-		}
-
-		if scopeReq.IsFirstCapture == 0 {
-			c.JSON(http.StatusOK, gin.H{"channel": scopeReq.Channel, "voltages": voltages, "times": 0})
-		} else {
-			c.JSON(http.StatusOK, gin.H{"channel": scopeReq.Channel, "voltages": voltages, "times": times})
-		}
-
+		response, err := GetScopeData(device, scopeReq)
+		respond(c, response, err)
 	}
 }
 
@@ -80,6 +118,23 @@ type WritePinRequest struct {
 	State int `json:"state"`
 }
 
+// WritePin drives a digital output high or low
+func WritePin(device *AnalogDiscoveryDevice, pinReq WritePinRequest) (gin.H, error) {
+	// Frontend sends 1, 2, 3, 4, but we need to convert it to 12, 13, 14, 15
+	pin := pinReq.Pin + 11
+
+	if !isPinAllowed(pin) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid pin, only %v are allowed", GetAllowed().OutputPins)}
+	}
+
+	if pinReq.State != 0 && pinReq.State != 1 {
+		return nil, ValidationError{Message: "Invalid state, only 0 or 1 are allowed"}
+	}
+	device.SetPinState(pin, pinReq.State == 1)
+
+	return gin.H{"message": "Pin set successfully"}, nil
+}
+
 func HandleWritePin(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var pinReq WritePinRequest
@@ -89,21 +144,8 @@ func HandleWritePin(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 			return
 		}
 
-		// Frontend sends 1, 2, 3, 4, but we need to convert it to 12, 13, 14, 15
-		pin := pinReq.Pin + 11
-
-		if !isPinAllowed(pin) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid pin, only %v are allowed", OutputPins)})
-			return
-		}
-
-		if pinReq.State != 0 && pinReq.State != 1 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state, only 0 or 1 are allowed"})
-			return
-		}
-		device.SetPinState(pin, pinReq.State == 1)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Pin set successfully"})
+		response, err := WritePin(device, pinReq)
+		respond(c, response, err)
 	}
 }
 
@@ -113,6 +155,21 @@ type WriteWavegenAmplitudeRequest struct {
 	Amplitude float64 `json:"amplitude"`
 }
 
+// SetWavegenAmplitude sets the amplitude of a wavegen channel
+func SetWavegenAmplitude(device *AnalogDiscoveryDevice, wavegenAmplitude WriteWavegenAmplitudeRequest) (gin.H, error) {
+	if !isChannelAllowed(wavegenAmplitude.Channel) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid channel, only %v are allowed", GetAllowed().Channels)}
+	}
+
+	if wavegenAmplitude.Amplitude < -5.0 || wavegenAmplitude.Amplitude > 5.0 {
+		return nil, ValidationError{Message: "Invalid amplitude, only values between -5.5 V and 5.5 are allowed"}
+	}
+
+	device.SetAnalogOutAmplitude(wavegenAmplitude.Channel, "AnalogOutNodeCarrier", wavegenAmplitude.Amplitude)
+
+	return gin.H{"message": "Analog out set successfully"}, nil
+}
+
 func HandleWavegenAmplitudeSet(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var wavegenAmplitude WriteWavegenAmplitudeRequest
@@ -123,20 +180,8 @@ func HandleWavegenAmplitudeSet(device *AnalogDiscoveryDevice) func(c *gin.Contex
 			return
 		}
 
-		if !isChannelAllowed(wavegenAmplitude.Channel) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid channel, only %v are allowed", outputChannels)})
-			return
-		}
-
-		if wavegenAmplitude.Amplitude < -5.0 || wavegenAmplitude.Amplitude > 5.0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amplitude, only values between -5.5 V and 5.5 are allowed"})
-			return
-		}
-
-		device.SetAnalogOutAmplitude(wavegenAmplitude.Channel, "AnalogOutNodeCarrier", wavegenAmplitude.Amplitude)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Analog out set successfully"})
-
+		response, err := SetWavegenAmplitude(device, wavegenAmplitude)
+		respond(c, response, err)
 	}
 }
 
@@ -146,6 +191,21 @@ type WriteWavegenDutyCycleRequest struct {
 	DutyCycle float64 `json:"dutyCycle"`
 }
 
+// SetWavegenDutyCycle sets the symmetry of a wavegen channel, in percent
+func SetWavegenDutyCycle(device *AnalogDiscoveryDevice, wavegenDutyCycle WriteWavegenDutyCycleRequest) (gin.H, error) {
+	if !isChannelAllowed(wavegenDutyCycle.Channel) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid channel, only %v are allowed", GetAllowed().Channels)}
+	}
+
+	if wavegenDutyCycle.DutyCycle < 0.0 || wavegenDutyCycle.DutyCycle > 100.0 {
+		return nil, ValidationError{Message: "Invalid duty cycle, only values between 0% and 100% are allowed"}
+	}
+
+	device.SetAnalogOutSymmetry(wavegenDutyCycle.Channel, "AnalogOutNodeCarrier", wavegenDutyCycle.DutyCycle)
+
+	return gin.H{"message": "Analog out set successfully"}, nil
+}
+
 func HandleWavegenDutyCycleSet(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var wavegenDutyCycle WriteWavegenDutyCycleRequest
@@ -156,20 +216,8 @@ func HandleWavegenDutyCycleSet(device *AnalogDiscoveryDevice) func(c *gin.Contex
 			return
 		}
 
-		if !isChannelAllowed(wavegenDutyCycle.Channel) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid channel, only %v are allowed", outputChannels)})
-			return
-		}
-
-		if wavegenDutyCycle.DutyCycle < 0.0 || wavegenDutyCycle.DutyCycle > 100.0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duty cycle, only values between 0% and 100% are allowed"})
-			return
-		}
-
-		device.SetAnalogOutSymmetry(wavegenDutyCycle.Channel, "AnalogOutNodeCarrier", wavegenDutyCycle.DutyCycle)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Analog out set successfully"})
-
+		response, err := SetWavegenDutyCycle(device, wavegenDutyCycle)
+		respond(c, response, err)
 	}
 }
 
@@ -179,6 +227,21 @@ type WriteWavegenFunctionRequest struct {
 	Function string `json:"function"`
 }
 
+// SetWavegenFunction sets the waveform of a wavegen channel
+func SetWavegenFunction(device *AnalogDiscoveryDevice, wavegenFunction WriteWavegenFunctionRequest) (gin.H, error) {
+	if !isChannelAllowed(wavegenFunction.Channel) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid channel, only %v are allowed", GetAllowed().Channels)}
+	}
+
+	if !isFunctionAllowed(wavegenFunction.Function) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid function, only %v are allowed", GetAllowed().Functions)}
+	}
+
+	device.SetAnalogOutNodeFunction(wavegenFunction.Channel, "AnalogOutNodeCarrier", wavegenFunction.Function)
+
+	return gin.H{"message": "Analog out function set successfully"}, nil
+}
+
 func HandleWavegenFunctionSet(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var wavegenFunction WriteWavegenFunctionRequest
@@ -189,20 +252,8 @@ func HandleWavegenFunctionSet(device *AnalogDiscoveryDevice) func(c *gin.Context
 			return
 		}
 
-		if !isChannelAllowed(wavegenFunction.Channel) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid channel, only %v are allowed", outputChannels)})
-			return
-		}
-
-		if !isFunctionAllowed(wavegenFunction.Function) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid function, only %v are allowed", wavegenFunction)})
-			return
-		}
-
-		device.SetAnalogOutNodeFunction(wavegenFunction.Channel, "AnalogOutNodeCarrier", wavegenFunction.Function)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Analog out function set successfully"})
-
+		response, err := SetWavegenFunction(device, wavegenFunction)
+		respond(c, response, err)
 	}
 }
 
@@ -212,6 +263,21 @@ type WriteWavegenFrequencyRequest struct {
 	Frequency float64 `json:"frequency"`
 }
 
+// SetWavegenFrequency sets the frequency of a wavegen channel
+func SetWavegenFrequency(device *AnalogDiscoveryDevice, wavegenFrequency WriteWavegenFrequencyRequest) (gin.H, error) {
+	if !isChannelAllowed(wavegenFrequency.Channel) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid channel, only %v are allowed", GetAllowed().Channels)}
+	}
+
+	if wavegenFrequency.Frequency <= 0 || wavegenFrequency.Frequency > 200000 {
+		return nil, ValidationError{Message: "Invalid frequency, only values between 0 Hz and 200 000 Hz are allowed"}
+	}
+
+	device.SetAnalogOutFrequency(wavegenFrequency.Channel, "AnalogOutNodeCarrier", wavegenFrequency.Frequency)
+
+	return gin.H{"message": "Analog out frequency set successfully"}, nil
+}
+
 func HandleWavegenFrequencySet(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var wavegenFrequency WriteWavegenFrequencyRequest
@@ -222,20 +288,8 @@ func HandleWavegenFrequencySet(device *AnalogDiscoveryDevice) func(c *gin.Contex
 			return
 		}
 
-		if !isChannelAllowed(wavegenFrequency.Channel) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid channel, only %v are allowed", outputChannels)})
-			return
-		}
-
-		if wavegenFrequency.Frequency <= 0 || wavegenFrequency.Frequency > 200000 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid frequency, only values between 0 Hz and 200 000 Hz are allowed"})
-			return
-		}
-
-		device.SetAnalogOutFrequency(wavegenFrequency.Channel, "AnalogOutNodeCarrier", wavegenFrequency.Frequency)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Analog out frequency set successfully"})
-
+		response, err := SetWavegenFrequency(device, wavegenFrequency)
+		respond(c, response, err)
 	}
 }
 
@@ -245,6 +299,17 @@ type WriteWavegenChannelEnableRequest struct {
 	IsEnabled int `json:"isEnabled"`
 }
 
+// EnableWavegenChannel turns a wavegen channel on or off
+func EnableWavegenChannel(device *AnalogDiscoveryDevice, wavegenEnableChannel WriteWavegenChannelEnableRequest) (gin.H, error) {
+	if !isChannelAllowed(wavegenEnableChannel.Channel) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid channel, only %v are allowed", GetAllowed().Channels)}
+	}
+
+	device.EnableAnalogOutChannel(wavegenEnableChannel.Channel, "AnalogOutNodeCarrier", wavegenEnableChannel.IsEnabled)
+
+	return gin.H{"message": "Analog out channel enabled/disabled successfully"}, nil
+}
+
 func HandleWavegenEnableChannel(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var wavegenEnableChannel WriteWavegenChannelEnableRequest
@@ -255,15 +320,8 @@ func HandleWavegenEnableChannel(device *AnalogDiscoveryDevice) func(c *gin.Conte
 			return
 		}
 
-		if !isChannelAllowed(wavegenEnableChannel.Channel) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid channel, only %v are allowed", outputChannels)})
-			return
-		}
-
-		device.EnableAnalogOutChannel(wavegenEnableChannel.Channel, "AnalogOutNodeCarrier", wavegenEnableChannel.IsEnabled)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Analog out channel enabled/disabled successfully"})
-
+		response, err := EnableWavegenChannel(device, wavegenEnableChannel)
+		respond(c, response, err)
 	}
 }
 
@@ -273,6 +331,17 @@ type WriteWavegenRunRequest struct {
 	IsStart int `json:"isStart"`
 }
 
+// RunWavegen starts or stops the generator of a wavegen channel
+func RunWavegen(device *AnalogDiscoveryDevice, wavegenRun WriteWavegenRunRequest) (gin.H, error) {
+	if !isChannelAllowed(wavegenRun.Channel) {
+		return nil, ValidationError{Message: fmt.Sprintf("Invalid channel, only %v are allowed", GetAllowed().Channels)}
+	}
+
+	device.GenerateWaveform(wavegenRun.Channel, "AnalogOutNodeCarrier", wavegenRun.IsStart)
+
+	return gin.H{"message": "Analog out channel generator started/stopped successfully"}, nil
+}
+
 func HandleWavegenRun(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var wavegenRun WriteWavegenRunRequest
@@ -283,15 +352,8 @@ func HandleWavegenRun(device *AnalogDiscoveryDevice) func(c *gin.Context) {
 			return
 		}
 
-		if !isChannelAllowed(wavegenRun.Channel) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid channel, only %v are allowed", outputChannels)})
-			return
-		}
-
-		device.GenerateWaveform(wavegenRun.Channel, "AnalogOutNodeCarrier", wavegenRun.IsStart)
-
-		c.JSON(http.StatusOK, gin.H{"message": "Analog out channel generator started/stopped successfully"})
-
+		response, err := RunWavegen(device, wavegenRun)
+		respond(c, response, err)
 	}
 }
 
@@ -305,26 +367,6 @@ func HandleLogicAnalyzerCapture(device *AnalogDiscoveryDevice) func(c *gin.Conte
 		}
 
 		resp, err := device.CaptureLogicTransitions(captureReq)
-		if err != nil {
-			var validationErr ValidationError
-			var deviceUnavailableErr DeviceUnavailableError
-			var deviceRuntimeErr DeviceRuntimeError
-
-			switch {
-			case errors.As(err, &validationErr):
-				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
-			case errors.Is(err, ErrLogicCaptureBusy):
-				c.JSON(http.StatusConflict, gin.H{"error": ErrLogicCaptureBusy.Error()})
-			case errors.As(err, &deviceUnavailableErr):
-				c.JSON(http.StatusServiceUnavailable, gin.H{"error": deviceUnavailableErr.Error()})
-			case errors.As(err, &deviceRuntimeErr):
-				c.JSON(http.StatusServiceUnavailable, gin.H{"error": deviceRuntimeErr.Error()})
-			default:
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			}
-			return
-		}
-
-		c.JSON(http.StatusOK, resp)
+		respond(c, resp, err)
 	}
 }
@@ -2,97 +2,20 @@ package analogdiscovery
 
 import (
 	"fmt"
-	"log"
+	"strings"
 	"sync"
 	"time"
-
-	"github.com/ebitengine/purego"
 )
 
-var dwf uintptr
-
-var FDwfEnum func(device_type_must_be_zero int32, count *int32)
-var FDwfDeviceConfigOpen func(index int32, auto int32, deviceHandle *int32)
-var FDwfEnumDeviceType func(index_minus_one int32, device_id *int32, device_rev *int32)
-var FDwfGetLastError func(error_number *int32)
-var FDwfGetLastErrorMsg func(error_message *byte)
-var FDwfDeviceClose func(deviceHandle int32)
-var FDwfDigitalIOOutputEnableGet func(deviceHandle int32, mask *uint16) int32
-var FDwfDigitalIOOutputEnableSet func(deviceHandle int32, mask uint16) int32
-var FDwfDigitalIOOutputGet func(deviceHandle int32, value *uint16) int32
-var FDwfDigitalIOOutputSet func(deviceHandle int32, value uint16) int32
-var FDwfAnalogInChannelCount func(deviceHandle int32, channels *int) int32
-var FDwfAnalogOutNodeEnableSet func(deviceHandle int32, idxChannel int, analogNode int, isEnabled int) int32
-var FDwfAnalogOutNodeFunctionSet func(deviceHandle int32, idxChannel int, analogNode int, function uint16) int32
-var FDwfAnalogOutNodeFrequencySet func(deviceHandle int32, idxChannel int, analogNode int, frequency float64) int32
-var FDwfAnalogOutNodeAmplitudeSet func(deviceHandle int32, idxChannel int, analogNode int, amplitude float64) int32
-var FDwfAnalogOutNodeOffsetSet func(deviceHandle int32, idxChannel int, analogNode int, offset float64) int32
-var FDwfAnalogOutNodeSymmetrySet func(deviceHandle int32, idxChannel int, analogNode int, percSymmetry float64) int32
-var FDwfAnalogOutConfigure func(deviceHandle int32, idxChannel int, fStart int)
-var FDwfAnalogOutNodeSymmetryGet func(deviceHandle int32, idxChannel int, analogNode int, pSymmetry *float64) int32
-var FDwfAnalogOutNodeEnableGet func(deviceHandle int32, idxChannel int, analogNode int, isEnabled *int) int32
-var FDwfAnalogOutNodeFunctionGet func(deviceHandle int32, idxChannel int, analogNode int, funcName *uint16) int32
-var FDwfAnalogOutNodeAmplitudeGet func(deviceHandle int32, idxChannel int, analogNode int, pAmplitude *float64) int32
-var FDwfAnalogOutNodeFrequencyGet func(deviceHandle int32, idxChannel int, analogNode int, pFrequency *float64) int32
-var FDwfAnalogOutNodeOffsetGet func(deviceHandle int32, idxChannel int, analogNode int, pOffset *float64) int32
-var FDwfAnalogInConfigure func(deviceHandle int32, fReconfigure int, fStart int)
-var FDwfAnalogInFrequencySet func(deviceHandle int32, frequency float64) int32
-var FDwfAnalogInBufferSizeSet func(deviceHandle int32, bufferSize int) int32
-var FDwfAnalogInChannelEnableSet func(deviceHandle int32, idxChannel int, isEnabled int) int32
-var FDwfAnalogInChannelRangeSet func(deviceHandle int32, idxChannel int, volts float64) int32
-var FDwfAnalogInStatus func(deviceHandle int32, readData int, pSTS *int) int32
-var FDwfAnalogInStatusData func(deviceHandle int32, idxChannel int, rgdVolts *float64, cdData int) int32
-var FDwfDigitalIOConfigure func(deviceHandle int32) int32
-
-// initializing Analog Discovery library
-func initDL() {
-	fmt.Println("Initializing Analog Discovery dwf")
-
-	var err error
-	dwf, err = purego.Dlopen("libdwf.so", purego.RTLD_NOW|purego.RTLD_GLOBAL)
-	if err != nil {
-		log.Fatalf("Error loading libdwf.so")
-	}
-
-	purego.RegisterLibFunc(&FDwfEnum, dwf, "FDwfEnum")
-	purego.RegisterLibFunc(&FDwfDeviceConfigOpen, dwf, "FDwfDeviceConfigOpen")
-	purego.RegisterLibFunc(&FDwfEnumDeviceType, dwf, "FDwfEnumDeviceType")
-	purego.RegisterLibFunc(&FDwfGetLastError, dwf, "FDwfGetLastError")
-	purego.RegisterLibFunc(&FDwfGetLastErrorMsg, dwf, "FDwfGetLastErrorMsg")
-	purego.RegisterLibFunc(&FDwfDeviceClose, dwf, "FDwfDeviceClose")
-	purego.RegisterLibFunc(&FDwfDigitalIOOutputEnableGet, dwf, "FDwfDigitalIOOutputEnableGet")
-	purego.RegisterLibFunc(&FDwfDigitalIOOutputEnableSet, dwf, "FDwfDigitalIOOutputEnableSet")
-	purego.RegisterLibFunc(&FDwfDigitalIOOutputGet, dwf, "FDwfDigitalIOOutputGet")
-	purego.RegisterLibFunc(&FDwfDigitalIOOutputSet, dwf, "FDwfDigitalIOOutputSet")
-	purego.RegisterLibFunc(&FDwfAnalogInChannelCount, dwf, "FDwfAnalogInChannelCount")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeEnableSet, dwf, "FDwfAnalogOutNodeEnableSet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeFunctionSet, dwf, "FDwfAnalogOutNodeFunctionSet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeFrequencySet, dwf, "FDwfAnalogOutNodeFrequencySet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeAmplitudeSet, dwf, "FDwfAnalogOutNodeAmplitudeSet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeSymmetrySet, dwf, "FDwfAnalogOutNodeSymmetrySet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeOffsetSet, dwf, "FDwfAnalogOutNodeOffsetSet")
-	purego.RegisterLibFunc(&FDwfAnalogOutConfigure, dwf, "FDwfAnalogOutConfigure")
-	purego.RegisterLibFunc(&FDwfAnalogInConfigure, dwf, "FDwfAnalogInConfigure")
-	purego.RegisterLibFunc(&FDwfAnalogInFrequencySet, dwf, "FDwfAnalogInFrequencySet")
-	purego.RegisterLibFunc(&FDwfAnalogInBufferSizeSet, dwf, "FDwfAnalogInBufferSizeSet")
-	purego.RegisterLibFunc(&FDwfAnalogInChannelEnableSet, dwf, "FDwfAnalogInChannelEnableSet")
-	purego.RegisterLibFunc(&FDwfAnalogInChannelRangeSet, dwf, "FDwfAnalogInChannelRangeSet")
-	purego.RegisterLibFunc(&FDwfAnalogInStatus, dwf, "FDwfAnalogInStatus")
-	purego.RegisterLibFunc(&FDwfAnalogInStatusData, dwf, "FDwfAnalogInStatusData")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeEnableGet, dwf, "FDwfAnalogOutNodeEnableGet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeSymmetryGet, dwf, "FDwfAnalogOutNodeSymmetryGet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeFrequencyGet, dwf, "FDwfAnalogOutNodeFrequencyGet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeAmplitudeGet, dwf, "FDwfAnalogOutNodeAmplitudeGet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeFunctionGet, dwf, "FDwfAnalogOutNodeFunctionGet")
-	purego.RegisterLibFunc(&FDwfAnalogOutNodeOffsetGet, dwf, "FDwfAnalogOutNodeOffsetGet")
-	purego.RegisterLibFunc(&FDwfDigitalIOConfigure, dwf, "FDwfDigitalIOConfigure")
-	registerDigitalInFunctions()
-}
-
 type AnalogDiscoveryDevice struct {
 	Handle           int32
+	DeviceID         int32
+	DeviceRevision   int32
+	SerialNumber     string
+	dwf              Backend
 	mu_gpio          sync.Mutex
 	mu_logicAnalyzer sync.Mutex
+	logicLimits      *LogicAnalyzerLimits
 }
 
 // get function by name
@@ -152,7 +75,7 @@ func GetAnalogOutNodeCarrierByName(name string) (int, error) {
 
 // config analog out with state
 func (ad *AnalogDiscoveryDevice) ConfigAnalogOut(idxChannel int, fStart int) error {
-	FDwfAnalogOutConfigure(ad.Handle, idxChannel, fStart)
+	ad.dwf.FDwfAnalogOutConfigure(ad.Handle, idxChannel, fStart)
 	return nil
 }
 
@@ -163,7 +86,7 @@ func (ad *AnalogDiscoveryDevice) GenerateWaveform(idxChannel int, analogNode str
 	fmt.Println("Trying to generate/stop waveform for channel ", idxChannel)
 
 	if ad.Handle == 0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("analog discovery handle is 0! %w", err)
 		}
 	}
@@ -175,54 +98,54 @@ func (ad *AnalogDiscoveryDevice) GenerateWaveform(idxChannel int, analogNode str
 	}
 
 	var isEnabled int
-	if FDwfAnalogOutNodeEnableGet(ad.Handle, idxChannel, a, &isEnabled) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeEnableGet(ad.Handle, idxChannel, a, &isEnabled) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting analog output enable: %w", err)
 		}
 	}
 
 	if isEnabled == 0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("this channel was not enabled: %w", err)
 		}
 	}
 
 	var amplitude float64
-	if FDwfAnalogOutNodeAmplitudeGet(ad.Handle, idxChannel, a, &amplitude) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeAmplitudeGet(ad.Handle, idxChannel, a, &amplitude) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting analog output amplitude: %w", err)
 		}
 	}
 
 	if amplitude < 0 || amplitude > 5 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("incorrect amplitude value: %w", err)
 		}
 	}
 	fmt.Println(amplitude)
 
 	var frequency float64
-	if FDwfAnalogOutNodeFrequencyGet(ad.Handle, idxChannel, a, &frequency) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeFrequencyGet(ad.Handle, idxChannel, a, &frequency) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting analog output frequency: %w", err)
 		}
 	}
 
 	if frequency <= 0.0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("incorrect frequency value: %w", err)
 		}
 	}
 
 	var funcName uint16
-	if FDwfAnalogOutNodeFunctionGet(ad.Handle, idxChannel, a, &funcName) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeFunctionGet(ad.Handle, idxChannel, a, &funcName) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting analog output function: %w", err)
 		}
 	}
 
 	if funcName == 0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("incorrect function value: %w", err)
 		}
 	}
@@ -237,14 +160,14 @@ func (ad *AnalogDiscoveryDevice) GenerateWaveform(idxChannel int, analogNode str
 	}
 
 	var symmetry float64
-	if FDwfAnalogOutNodeSymmetryGet(ad.Handle, idxChannel, a, &symmetry) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeSymmetryGet(ad.Handle, idxChannel, a, &symmetry) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting analog output symmetry: %w", err)
 		}
 	}
 
 	if symmetry < 0.0 || symmetry > 100.0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("incorrect symmetry value: %w", err)
 		}
 	}
@@ -280,7 +203,7 @@ func (ad *AnalogDiscoveryDevice) ReadScopeValues(channel int, isFirstCapture int
 	var timeValues [600]int64
 
 	for i < 600 {
-		FDwfAnalogInStatus(ad.Handle, 1, &sts)
+		ad.dwf.FDwfAnalogInStatus(ad.Handle, 1, &sts)
 
 		var freq int64 = int64(samplingFrequency)
 		// convert to microseconds
@@ -289,7 +212,7 @@ func (ad *AnalogDiscoveryDevice) ReadScopeValues(channel int, isFirstCapture int
 		i++
 	}
 
-	FDwfAnalogInStatusData(ad.Handle, channel, &rgdSamples[0], 600)
+	ad.dwf.FDwfAnalogInStatusData(ad.Handle, channel, &rgdSamples[0], 600)
 	i = 0
 	for i < 600 {
 		i++
@@ -298,9 +221,9 @@ func (ad *AnalogDiscoveryDevice) ReadScopeValues(channel int, isFirstCapture int
 }
 
 // check if there is error
-func checkError() error {
+func checkError(dwf Backend) error {
 	errMsg := make([]byte, 512)
-	FDwfGetLastErrorMsg(&errMsg[0])
+	dwf.FDwfGetLastErrorMsg(&errMsg[0])
 	asciiString := string(errMsg)
 	fmt.Println("Error: ", asciiString)
 	if asciiString != "" {
@@ -309,52 +232,61 @@ func checkError() error {
 	return nil
 }
 
-// connect to the Analog Discovery device
-func CreateDevice() (*AnalogDiscoveryDevice, error) {
-	initDL()
-
+// connect to the Analog Discovery device through the given backend
+func CreateDevice(dwf Backend) (*AnalogDiscoveryDevice, error) {
 	deviceType := int32(0)
 	var deviceCount int32
 
-	if FDwfEnum(deviceType, &deviceCount); deviceCount <= 0 {
+	if dwf.FDwfEnum(deviceType, &deviceCount); deviceCount <= 0 {
 		return nil, fmt.Errorf("no Analog Discovery devices found")
 	}
 	fmt.Println("Device count: ", deviceCount)
 
 	var deviceHandle int32
+	var deviceId int32
+	var deviceRev int32
+	var serialNumber string
 
 	index := int32(0)
 	for deviceHandle == 0 && index < deviceCount {
-		FDwfDeviceConfigOpen(index, 0, &deviceHandle)
+		dwf.FDwfDeviceConfigOpen(index, 0, &deviceHandle)
 		index++
 	}
 
 	if deviceHandle != int32(0) {
-		var deviceId int32
-		var deviceRev int32
-		if FDwfEnumDeviceType(index-1, &deviceId, &deviceRev); deviceId == int32(3) {
+		if dwf.FDwfEnumDeviceType(index-1, &deviceId, &deviceRev); deviceId == int32(3) {
 			fmt.Println("Found Analog Discovery 2")
 		} else {
 			fmt.Println("Found Analog Discovery, but not an Analog Discovery 2")
 		}
+
+		sn := make([]byte, 32)
+		dwf.FDwfEnumSN(index-1, &sn[0])
+		serialNumber = strings.TrimRight(string(sn), "\x00")
 	}
 
 	if deviceHandle == int32(0) {
 		var err_nr int32
-		if FDwfGetLastError(&err_nr); err_nr != int32(0) {
-			err := checkError()
+		if dwf.FDwfGetLastError(&err_nr); err_nr != int32(0) {
+			err := checkError(dwf)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
-	return &AnalogDiscoveryDevice{Handle: deviceHandle}, nil
+	return &AnalogDiscoveryDevice{
+		Handle:         deviceHandle,
+		DeviceID:       deviceId,
+		DeviceRevision: deviceRev,
+		SerialNumber:   serialNumber,
+		dwf:            dwf,
+	}, nil
 }
 
 // close the connection to device
 func (ad *AnalogDiscoveryDevice) Close() {
 	if ad.Handle != 0 {
-		FDwfDeviceClose(ad.Handle)
+		ad.dwf.FDwfDeviceClose(ad.Handle)
 	}
 	ad.Handle = 0
 }
@@ -368,13 +300,13 @@ func (ad *AnalogDiscoveryDevice) EnableAnalogOutChannel(indexCh int, nodeName st
 	if a == -1 {
 		return fmt.Errorf("no such analog out node")
 	}
-	if FDwfAnalogOutNodeEnableGet(ad.Handle, indexCh, a, enabled) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeEnableGet(ad.Handle, indexCh, a, enabled) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting analog output node enable: %w", err)
 		}
 	}
-	if FDwfAnalogOutNodeEnableSet(ad.Handle, indexCh, a, isEnabled) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeEnableSet(ad.Handle, indexCh, a, isEnabled) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog output node enable: %w", err)
 		}
 	}
@@ -389,13 +321,13 @@ func (ad *AnalogDiscoveryDevice) SetAnalogOutNodeFunction(indexCh int, nodeName
 		return fmt.Errorf("no such analog out node or function")
 	}
 
-	if FDwfAnalogOutNodeFunctionSet(ad.Handle, indexCh, a, f) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeFunctionSet(ad.Handle, indexCh, a, f) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog output node function: %w", err)
 		}
 	}
 	var mmode uint16
-	FDwfAnalogOutNodeFunctionGet(ad.Handle, indexCh, a, &mmode)
+	ad.dwf.FDwfAnalogOutNodeFunctionGet(ad.Handle, indexCh, a, &mmode)
 	fmt.Println("mode")
 	fmt.Println(mmode)
 	return nil
@@ -411,8 +343,8 @@ func (ad *AnalogDiscoveryDevice) SetAnalogOutOffset(indexCh int, nodeName string
 	if offset < 0 || offset > 3.0 {
 		return fmt.Errorf("incorrect or too high offset")
 	}
-	if FDwfAnalogOutNodeOffsetSet(ad.Handle, indexCh, a, offset) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeOffsetSet(ad.Handle, indexCh, a, offset) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog output offset: %w", err)
 		}
 	}
@@ -426,8 +358,8 @@ func (ad *AnalogDiscoveryDevice) SetAnalogOutSymmetry(indexCh int, nodeName stri
 	if a == -1 {
 		return fmt.Errorf("analog out node is incorrect")
 	}
-	if FDwfAnalogOutNodeSymmetrySet(ad.Handle, indexCh, a, percSymmetry) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeSymmetrySet(ad.Handle, indexCh, a, percSymmetry) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog output symmetry: %w", err)
 		}
 	}
@@ -441,8 +373,8 @@ func (ad *AnalogDiscoveryDevice) SetAnalogOutFrequency(indexCh int, nodeName str
 	if a == -1 {
 		return fmt.Errorf("analog out node is incorrect")
 	}
-	if FDwfAnalogOutNodeFrequencySet(ad.Handle, indexCh, a, frequencyValue) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogOutNodeFrequencySet(ad.Handle, indexCh, a, frequencyValue) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog output function: %w", err)
 		}
 	}
@@ -457,14 +389,14 @@ func (ad *AnalogDiscoveryDevice) SetAnalogOutAmplitude(indexCh int, nodeName str
 		return fmt.Errorf("analog out node is incorrect")
 	}
 	fmt.Println(amplitudeValue)
-	FDwfAnalogOutNodeAmplitudeSet(ad.Handle, indexCh, a, amplitudeValue)
-	if FDwfAnalogOutNodeAmplitudeSet(ad.Handle, indexCh, a, amplitudeValue) == 0 {
-		if err := checkError(); err != nil {
+	ad.dwf.FDwfAnalogOutNodeAmplitudeSet(ad.Handle, indexCh, a, amplitudeValue)
+	if ad.dwf.FDwfAnalogOutNodeAmplitudeSet(ad.Handle, indexCh, a, amplitudeValue) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog output amplitude: %w", err)
 		}
 	}
 	var frequency float64
-	FDwfAnalogOutNodeAmplitudeGet(ad.Handle, indexCh, a, &frequency)
+	ad.dwf.FDwfAnalogOutNodeAmplitudeGet(ad.Handle, indexCh, a, &frequency)
 	fmt.Println("real value")
 	fmt.Println(frequency)
 
@@ -475,22 +407,22 @@ func (ad *AnalogDiscoveryDevice) SetAnalogOutAmplitude(indexCh int, nodeName str
 
 // reconfig analog in - start
 func (ad *AnalogDiscoveryDevice) ReConfigAnalogInStart() {
-	FDwfAnalogInConfigure(ad.Handle, 1, 1)
+	ad.dwf.FDwfAnalogInConfigure(ad.Handle, 1, 1)
 }
 
 // reconfig analog in - stop
 func (ad *AnalogDiscoveryDevice) ReConfigAnalogInStop() {
-	FDwfAnalogInConfigure(ad.Handle, 1, 0)
+	ad.dwf.FDwfAnalogInConfigure(ad.Handle, 1, 0)
 }
 
 // config analog in - start
 func (ad *AnalogDiscoveryDevice) ConfigAnalogInStart() {
-	FDwfAnalogInConfigure(ad.Handle, 0, 1)
+	ad.dwf.FDwfAnalogInConfigure(ad.Handle, 0, 1)
 }
 
 // config analog in - stop
 func (ad *AnalogDiscoveryDevice) ConfigAnalogInStop() {
-	FDwfAnalogInConfigure(ad.Handle, 0, 0)
+	ad.dwf.FDwfAnalogInConfigure(ad.Handle, 0, 0)
 }
 
 // set frequency of analog in
@@ -499,8 +431,8 @@ func (ad *AnalogDiscoveryDevice) SetAnalogInFrequency(frequency float64) error {
 	if frequency <= 0 || frequency > 25000000.0 {
 		return fmt.Errorf("incorrect or too high frequency")
 	}
-	if FDwfAnalogInFrequencySet(ad.Handle, frequency) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogInFrequencySet(ad.Handle, frequency) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog input frequency: %w", err)
 		}
 	}
@@ -512,8 +444,8 @@ func (ad *AnalogDiscoveryDevice) SetAnalogInBufferSize(bufferSize int) error {
 	if bufferSize <= 0 {
 		return fmt.Errorf("buffer size is incorrect")
 	}
-	if FDwfAnalogInBufferSizeSet(ad.Handle, bufferSize) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogInBufferSizeSet(ad.Handle, bufferSize) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog input buffer size: %w", err)
 		}
 	}
@@ -522,7 +454,7 @@ func (ad *AnalogDiscoveryDevice) SetAnalogInBufferSize(bufferSize int) error {
 
 // enable / disable specified analog in channel
 func (ad *AnalogDiscoveryDevice) EnableAnalogInChannel(indexCh int, isEnabled int) {
-	FDwfAnalogInChannelEnableSet(ad.Handle, indexCh, isEnabled)
+	ad.dwf.FDwfAnalogInChannelEnableSet(ad.Handle, indexCh, isEnabled)
 }
 
 // set channel range of analog in
@@ -530,8 +462,8 @@ func (ad *AnalogDiscoveryDevice) SetAnalogInChannelRange(indexCh int, volts floa
 	if volts >= 10.0 {
 		return fmt.Errorf("channel range is incorrect")
 	}
-	if FDwfAnalogInChannelRangeSet(ad.Handle, indexCh, volts) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfAnalogInChannelRangeSet(ad.Handle, indexCh, volts) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting analog input channel range: %w", err)
 		}
 	}
@@ -547,8 +479,8 @@ func (ad *AnalogDiscoveryDevice) SetPinMode(pin int, mode bool) error {
 	ad.mu_gpio.Lock()
 	defer ad.mu_gpio.Unlock()
 
-	if FDwfDigitalIOOutputEnableGet(ad.Handle, &mask) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfDigitalIOOutputEnableGet(ad.Handle, &mask) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting digital IO output enable: %w", err)
 		}
 	}
@@ -561,11 +493,11 @@ func (ad *AnalogDiscoveryDevice) SetPinMode(pin int, mode bool) error {
 
 	fmt.Printf("SetPinMode Mask: %016b\n", mask)
 
-	outputEnableResult := FDwfDigitalIOOutputEnableSet(ad.Handle, mask)
+	outputEnableResult := ad.dwf.FDwfDigitalIOOutputEnableSet(ad.Handle, mask)
 	fmt.Printf("SetPinMode output enable result: %d\n", outputEnableResult)
 
 	if outputEnableResult == 0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting digital IO output enable: %w", err)
 		}
 	}
@@ -581,15 +513,15 @@ func (ad *AnalogDiscoveryDevice) SetPinState(pin int, value bool) error {
 	ad.mu_gpio.Lock()
 	defer ad.mu_gpio.Unlock()
 
-	if FDwfDigitalIOOutputEnableGet(ad.Handle, &_pinModeMask) == 0 { // TEST LINE
-		if err := checkError(); err != nil { // TEST LINE
+	if ad.dwf.FDwfDigitalIOOutputEnableGet(ad.Handle, &_pinModeMask) == 0 { // TEST LINE
+		if err := checkError(ad.dwf); err != nil { // TEST LINE
 			return fmt.Errorf("error getting digital IO output enable: %w", err) // TEST LINE
 		} // TEST LINE
 	} // TEST LINE
 	fmt.Printf("Pin mode mask: %016b\n", _pinModeMask) // TEST LINE
 
-	if FDwfDigitalIOOutputGet(ad.Handle, &mask) == 0 {
-		if err := checkError(); err != nil {
+	if ad.dwf.FDwfDigitalIOOutputGet(ad.Handle, &mask) == 0 {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error getting digital IO output enable: %w", err)
 		}
 	}
@@ -602,20 +534,20 @@ func (ad *AnalogDiscoveryDevice) SetPinState(pin int, value bool) error {
 
 	fmt.Printf("Mask: %016b\n", mask)
 
-	outputSetResult := FDwfDigitalIOOutputSet(ad.Handle, mask)
+	outputSetResult := ad.dwf.FDwfDigitalIOOutputSet(ad.Handle, mask)
 	fmt.Printf("Output set result: %d\n", outputSetResult)
 
 	if outputSetResult == 0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error setting digital IO output: %w", err)
 		}
 	}
 
-	ioConfigureResult := FDwfDigitalIOConfigure(ad.Handle)
+	ioConfigureResult := ad.dwf.FDwfDigitalIOConfigure(ad.Handle)
 	fmt.Printf("IO configure result: %d\n", ioConfigureResult)
 
 	if ioConfigureResult == 0 {
-		if err := checkError(); err != nil {
+		if err := checkError(ad.dwf); err != nil {
 			return fmt.Errorf("error configuring digital IO: %w", err)
 		}
 	}
@@ -624,3 +556,19 @@ func (ad *AnalogDiscoveryDevice) SetPinState(pin int, value bool) error {
 
 	return nil
 }
+
+// Probe reads the digital IO output enable mask, which fails once the device is
+// unplugged. It changes nothing on the device.
+func (ad *AnalogDiscoveryDevice) Probe() error {
+	var mask uint16
+
+	ad.mu_gpio.Lock()
+	defer ad.mu_gpio.Unlock()
+
+	if ad.dwf.FDwfDigitalIOOutputEnableGet(ad.Handle, &mask) == 0 {
+		if err := checkError(ad.dwf); err != nil {
+			return fmt.Errorf("error getting digital IO output enable: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package analogdiscovery
+
+import (
+	"errors"
+	"fmt"
+)
+
+// wavegenChannelCount is the number of wavegen channels on the Analog Discovery 2
+const wavegenChannelCount = 2
+
+// StopWavegen stops and disables every wavegen channel, whether clients may use it or not
+func (ad *AnalogDiscoveryDevice) StopWavegen() error {
+	carrier, _ := GetAnalogOutNodeCarrierByName("AnalogOutNodeCarrier")
+
+	var errs []error
+	for channel := 0; channel < wavegenChannelCount; channel++ {
+		ad.dwf.FDwfAnalogOutConfigure(ad.Handle, channel, 0)
+		if ad.dwf.FDwfAnalogOutNodeEnableSet(ad.Handle, channel, carrier, 0) == 0 {
+			if err := checkError(ad.dwf); err != nil {
+				errs = append(errs, fmt.Errorf("error disabling wavegen channel %d: %w", channel, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DriveOutputsLow sets every digital output low. The pins stay outputs.
+func (ad *AnalogDiscoveryDevice) DriveOutputsLow() error {
+	ad.mu_gpio.Lock()
+	defer ad.mu_gpio.Unlock()
+
+	if ad.dwf.FDwfDigitalIOOutputSet(ad.Handle, 0) == 0 {
+		if err := checkError(ad.dwf); err != nil {
+			return fmt.Errorf("error setting digital IO output: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+package analogdiscovery
+
+import (
+	"fmt"
+
+	"github.com/ebitengine/purego"
+)
+
+// libdwfBackend binds Backend to the WaveForms SDK shared library through purego
+type libdwfBackend struct {
+	fnEnum             func(deviceType int32, count *int32)
+	fnDeviceConfigOpen func(index int32, auto int32, deviceHandle *int32)
+	fnEnumDeviceType   func(index int32, deviceId *int32, deviceRev *int32)
+	fnEnumSN           func(index int32, serialNumber *byte)
+	fnGetLastError     func(errorNumber *int32)
+	fnGetLastErrorMsg  func(errorMessage *byte)
+	fnDeviceClose      func(deviceHandle int32)
+
+	fnDigitalIOOutputEnableGet func(deviceHandle int32, mask *uint16) int32
+	fnDigitalIOOutputEnableSet func(deviceHandle int32, mask uint16) int32
+	fnDigitalIOOutputGet       func(deviceHandle int32, value *uint16) int32
+	fnDigitalIOOutputSet       func(deviceHandle int32, value uint16) int32
+	fnDigitalIOConfigure       func(deviceHandle int32) int32
+
+	fnAnalogOutNodeEnableSet    func(deviceHandle int32, idxChannel int, analogNode int, isEnabled int) int32
+	fnAnalogOutNodeFunctionSet  func(deviceHandle int32, idxChannel int, analogNode int, function uint16) int32
+	fnAnalogOutNodeFrequencySet func(deviceHandle int32, idxChannel int, analogNode int, frequency float64) int32
+	fnAnalogOutNodeAmplitudeSet func(deviceHandle int32, idxChannel int, analogNode int, amplitude float64) int32
+	fnAnalogOutNodeOffsetSet    func(deviceHandle int32, idxChannel int, analogNode int, offset float64) int32
+	fnAnalogOutNodeSymmetrySet  func(deviceHandle int32, idxChannel int, analogNode int, percSymmetry float64) int32
+	fnAnalogOutConfigure        func(deviceHandle int32, idxChannel int, fStart int)
+	fnAnalogOutNodeSymmetryGet  func(deviceHandle int32, idxChannel int, analogNode int, pSymmetry *float64) int32
+	fnAnalogOutNodeEnableGet    func(deviceHandle int32, idxChannel int, analogNode int, isEnabled *int) int32
+	fnAnalogOutNodeFunctionGet  func(deviceHandle int32, idxChannel int, analogNode int, funcName *uint16) int32
+	fnAnalogOutNodeAmplitudeGet func(deviceHandle int32, idxChannel int, analogNode int, pAmplitude *float64) int32
+	fnAnalogOutNodeFrequencyGet func(deviceHandle int32, idxChannel int, analogNode int, pFrequency *float64) int32
+	fnAnalogOutNodeOffsetGet    func(deviceHandle int32, idxChannel int, analogNode int, pOffset *float64) int32
+
+	fnAnalogInChannelCount     func(deviceHandle int32, channels *int) int32
+	fnAnalogInConfigure        func(deviceHandle int32, fReconfigure int, fStart int)
+	fnAnalogInFrequencySet     func(deviceHandle int32, frequency float64) int32
+	fnAnalogInBufferSizeSet    func(deviceHandle int32, bufferSize int) int32
+	fnAnalogInChannelEnableSet func(deviceHandle int32, idxChannel int, isEnabled int) int32
+	fnAnalogInChannelRangeSet  func(deviceHandle int32, idxChannel int, volts float64) int32
+	fnAnalogInStatus           func(deviceHandle int32, readData int, pSTS *int) int32
+	fnAnalogInStatusData       func(deviceHandle int32, idxChannel int, rgdVolts *float64, cdData int) int32
+
+	fnDigitalInReset                 func(deviceHandle int32) int32
+	fnDigitalInConfigure             func(deviceHandle int32, fReconfigure int, fStart int) int32
+	fnDigitalInStatus                func(deviceHandle int32, fReadData int, pStatus *byte) int32
+	fnDigitalInStatusData            func(deviceHandle int32, rgBytes *byte, countOfDataBytes int) int32
+	fnDigitalInStatusRecord          func(deviceHandle int32, pDataAvailable *int, pDataLost *int, pDataCorrupt *int) int32
+	fnDigitalInInternalClockInfo     func(deviceHandle int32, phzFreq *float64) int32
+	fnDigitalInDividerInfo           func(deviceHandle int32, pMax *int) int32
+	fnDigitalInDividerSet            func(deviceHandle int32, divider int) int32
+	fnDigitalInDividerGet            func(deviceHandle int32, pDivider *int) int32
+	fnDigitalInSampleFormatSet       func(deviceHandle int32, bits int) int32
+	fnDigitalInBufferSizeInfo        func(deviceHandle int32, pMax *int) int32
+	fnDigitalInBufferSizeSet         func(deviceHandle int32, bufferSize int) int32
+	fnDigitalInBufferSizeGet         func(deviceHandle int32, pBufferSize *int) int32
+	fnDigitalInAcquisitionModeSet    func(deviceHandle int32, acqMode int) int32
+	fnDigitalInTriggerSourceSet      func(deviceHandle int32, trigsrc uint8) int32
+	fnDigitalInTriggerSlopeSet       func(deviceHandle int32, slope int) int32
+	fnDigitalInTriggerPositionSet    func(deviceHandle int32, samples uint32) int32
+	fnDigitalInTriggerPrefillSet     func(deviceHandle int32, samples uint32) int32
+	fnDigitalInTriggerAutoTimeoutSet func(deviceHandle int32, secTimeout float64) int32
+	fnDigitalInTriggerSet            func(deviceHandle int32, levelLow uint32, levelHigh uint32, edgeRise uint32, edgeFall uint32) int32
+}
+
+// initializing Analog Discovery library
+func NewLibdwfBackend() (Backend, error) {
+	fmt.Println("Initializing Analog Discovery dwf")
+
+	dwf, err := purego.Dlopen("libdwf.so", purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		return nil, fmt.Errorf("error loading libdwf.so: %w", err)
+	}
+
+	b := &libdwfBackend{}
+	purego.RegisterLibFunc(&b.fnEnum, dwf, "FDwfEnum")
+	purego.RegisterLibFunc(&b.fnDeviceConfigOpen, dwf, "FDwfDeviceConfigOpen")
+	purego.RegisterLibFunc(&b.fnEnumDeviceType, dwf, "FDwfEnumDeviceType")
+	purego.RegisterLibFunc(&b.fnEnumSN, dwf, "FDwfEnumSN")
+	purego.RegisterLibFunc(&b.fnGetLastError, dwf, "FDwfGetLastError")
+	purego.RegisterLibFunc(&b.fnGetLastErrorMsg, dwf, "FDwfGetLastErrorMsg")
+	purego.RegisterLibFunc(&b.fnDeviceClose, dwf, "FDwfDeviceClose")
+
+	purego.RegisterLibFunc(&b.fnDigitalIOOutputEnableGet, dwf, "FDwfDigitalIOOutputEnableGet")
+	purego.RegisterLibFunc(&b.fnDigitalIOOutputEnableSet, dwf, "FDwfDigitalIOOutputEnableSet")
+	purego.RegisterLibFunc(&b.fnDigitalIOOutputGet, dwf, "FDwfDigitalIOOutputGet")
+	purego.RegisterLibFunc(&b.fnDigitalIOOutputSet, dwf, "FDwfDigitalIOOutputSet")
+	purego.RegisterLibFunc(&b.fnDigitalIOConfigure, dwf, "FDwfDigitalIOConfigure")
+
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeEnableSet, dwf, "FDwfAnalogOutNodeEnableSet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeFunctionSet, dwf, "FDwfAnalogOutNodeFunctionSet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeFrequencySet, dwf, "FDwfAnalogOutNodeFrequencySet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeAmplitudeSet, dwf, "FDwfAnalogOutNodeAmplitudeSet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeOffsetSet, dwf, "FDwfAnalogOutNodeOffsetSet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeSymmetrySet, dwf, "FDwfAnalogOutNodeSymmetrySet")
+	purego.RegisterLibFunc(&b.fnAnalogOutConfigure, dwf, "FDwfAnalogOutConfigure")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeSymmetryGet, dwf, "FDwfAnalogOutNodeSymmetryGet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeEnableGet, dwf, "FDwfAnalogOutNodeEnableGet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeFunctionGet, dwf, "FDwfAnalogOutNodeFunctionGet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeAmplitudeGet, dwf, "FDwfAnalogOutNodeAmplitudeGet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeFrequencyGet, dwf, "FDwfAnalogOutNodeFrequencyGet")
+	purego.RegisterLibFunc(&b.fnAnalogOutNodeOffsetGet, dwf, "FDwfAnalogOutNodeOffsetGet")
+
+	purego.RegisterLibFunc(&b.fnAnalogInChannelCount, dwf, "FDwfAnalogInChannelCount")
+	purego.RegisterLibFunc(&b.fnAnalogInConfigure, dwf, "FDwfAnalogInConfigure")
+	purego.RegisterLibFunc(&b.fnAnalogInFrequencySet, dwf, "FDwfAnalogInFrequencySet")
+	purego.RegisterLibFunc(&b.fnAnalogInBufferSizeSet, dwf, "FDwfAnalogInBufferSizeSet")
+	purego.RegisterLibFunc(&b.fnAnalogInChannelEnableSet, dwf, "FDwfAnalogInChannelEnableSet")
+	purego.RegisterLibFunc(&b.fnAnalogInChannelRangeSet, dwf, "FDwfAnalogInChannelRangeSet")
+	purego.RegisterLibFunc(&b.fnAnalogInStatus, dwf, "FDwfAnalogInStatus")
+	purego.RegisterLibFunc(&b.fnAnalogInStatusData, dwf, "FDwfAnalogInStatusData")
+
+	purego.RegisterLibFunc(&b.fnDigitalInReset, dwf, "FDwfDigitalInReset")
+	purego.RegisterLibFunc(&b.fnDigitalInConfigure, dwf, "FDwfDigitalInConfigure")
+	purego.RegisterLibFunc(&b.fnDigitalInStatus, dwf, "FDwfDigitalInStatus")
+	purego.RegisterLibFunc(&b.fnDigitalInStatusData, dwf, "FDwfDigitalInStatusData")
+	purego.RegisterLibFunc(&b.fnDigitalInStatusRecord, dwf, "FDwfDigitalInStatusRecord")
+	purego.RegisterLibFunc(&b.fnDigitalInInternalClockInfo, dwf, "FDwfDigitalInInternalClockInfo")
+	purego.RegisterLibFunc(&b.fnDigitalInDividerInfo, dwf, "FDwfDigitalInDividerInfo")
+	purego.RegisterLibFunc(&b.fnDigitalInDividerSet, dwf, "FDwfDigitalInDividerSet")
+	purego.RegisterLibFunc(&b.fnDigitalInDividerGet, dwf, "FDwfDigitalInDividerGet")
+	purego.RegisterLibFunc(&b.fnDigitalInSampleFormatSet, dwf, "FDwfDigitalInSampleFormatSet")
+	purego.RegisterLibFunc(&b.fnDigitalInBufferSizeInfo, dwf, "FDwfDigitalInBufferSizeInfo")
+	purego.RegisterLibFunc(&b.fnDigitalInBufferSizeSet, dwf, "FDwfDigitalInBufferSizeSet")
+	purego.RegisterLibFunc(&b.fnDigitalInBufferSizeGet, dwf, "FDwfDigitalInBufferSizeGet")
+	purego.RegisterLibFunc(&b.fnDigitalInAcquisitionModeSet, dwf, "FDwfDigitalInAcquisitionModeSet")
+	purego.RegisterLibFunc(&b.fnDigitalInTriggerSourceSet, dwf, "FDwfDigitalInTriggerSourceSet")
+	purego.RegisterLibFunc(&b.fnDigitalInTriggerSlopeSet, dwf, "FDwfDigitalInTriggerSlopeSet")
+	purego.RegisterLibFunc(&b.fnDigitalInTriggerPositionSet, dwf, "FDwfDigitalInTriggerPositionSet")
+	purego.RegisterLibFunc(&b.fnDigitalInTriggerPrefillSet, dwf, "FDwfDigitalInTriggerPrefillSet")
+	purego.RegisterLibFunc(&b.fnDigitalInTriggerAutoTimeoutSet, dwf, "FDwfDigitalInTriggerAutoTimeoutSet")
+	purego.RegisterLibFunc(&b.fnDigitalInTriggerSet, dwf, "FDwfDigitalInTriggerSet")
+
+	return b, nil
+}
+
+func (b *libdwfBackend) FDwfEnum(deviceType int32, count *int32) {
+	b.fnEnum(deviceType, count)
+}
+
+func (b *libdwfBackend) FDwfDeviceConfigOpen(index int32, auto int32, deviceHandle *int32) {
+	b.fnDeviceConfigOpen(index, auto, deviceHandle)
+}
+
+func (b *libdwfBackend) FDwfEnumDeviceType(index int32, deviceId *int32, deviceRev *int32) {
+	b.fnEnumDeviceType(index, deviceId, deviceRev)
+}
+
+func (b *libdwfBackend) FDwfEnumSN(index int32, serialNumber *byte) {
+	b.fnEnumSN(index, serialNumber)
+}
+
+func (b *libdwfBackend) FDwfGetLastError(errorNumber *int32) {
+	b.fnGetLastError(errorNumber)
+}
+
+func (b *libdwfBackend) FDwfGetLastErrorMsg(errorMessage *byte) {
+	b.fnGetLastErrorMsg(errorMessage)
+}
+
+func (b *libdwfBackend) FDwfDeviceClose(deviceHandle int32) {
+	b.fnDeviceClose(deviceHandle)
+}
+
+func (b *libdwfBackend) FDwfDigitalIOOutputEnableGet(deviceHandle int32, mask *uint16) int32 {
+	return b.fnDigitalIOOutputEnableGet(deviceHandle, mask)
+}
+
+func (b *libdwfBackend) FDwfDigitalIOOutputEnableSet(deviceHandle int32, mask uint16) int32 {
+	return b.fnDigitalIOOutputEnableSet(deviceHandle, mask)
+}
+
+func (b *libdwfBackend) FDwfDigitalIOOutputGet(deviceHandle int32, value *uint16) int32 {
+	return b.fnDigitalIOOutputGet(deviceHandle, value)
+}
+
+func (b *libdwfBackend) FDwfDigitalIOOutputSet(deviceHandle int32, value uint16) int32 {
+	return b.fnDigitalIOOutputSet(deviceHandle, value)
+}
+
+func (b *libdwfBackend) FDwfDigitalIOConfigure(deviceHandle int32) int32 {
+	return b.fnDigitalIOConfigure(deviceHandle)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeEnableSet(deviceHandle int32, idxChannel int, analogNode int, isEnabled int) int32 {
+	return b.fnAnalogOutNodeEnableSet(deviceHandle, idxChannel, analogNode, isEnabled)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeFunctionSet(deviceHandle int32, idxChannel int, analogNode int, function uint16) int32 {
+	return b.fnAnalogOutNodeFunctionSet(deviceHandle, idxChannel, analogNode, function)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeFrequencySet(deviceHandle int32, idxChannel int, analogNode int, frequency float64) int32 {
+	return b.fnAnalogOutNodeFrequencySet(deviceHandle, idxChannel, analogNode, frequency)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeAmplitudeSet(deviceHandle int32, idxChannel int, analogNode int, amplitude float64) int32 {
+	return b.fnAnalogOutNodeAmplitudeSet(deviceHandle, idxChannel, analogNode, amplitude)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeOffsetSet(deviceHandle int32, idxChannel int, analogNode int, offset float64) int32 {
+	return b.fnAnalogOutNodeOffsetSet(deviceHandle, idxChannel, analogNode, offset)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeSymmetrySet(deviceHandle int32, idxChannel int, analogNode int, percSymmetry float64) int32 {
+	return b.fnAnalogOutNodeSymmetrySet(deviceHandle, idxChannel, analogNode, percSymmetry)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutConfigure(deviceHandle int32, idxChannel int, fStart int) {
+	b.fnAnalogOutConfigure(deviceHandle, idxChannel, fStart)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeSymmetryGet(deviceHandle int32, idxChannel int, analogNode int, pSymmetry *float64) int32 {
+	return b.fnAnalogOutNodeSymmetryGet(deviceHandle, idxChannel, analogNode, pSymmetry)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeEnableGet(deviceHandle int32, idxChannel int, analogNode int, isEnabled *int) int32 {
+	return b.fnAnalogOutNodeEnableGet(deviceHandle, idxChannel, analogNode, isEnabled)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeFunctionGet(deviceHandle int32, idxChannel int, analogNode int, funcName *uint16) int32 {
+	return b.fnAnalogOutNodeFunctionGet(deviceHandle, idxChannel, analogNode, funcName)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeAmplitudeGet(deviceHandle int32, idxChannel int, analogNode int, pAmplitude *float64) int32 {
+	return b.fnAnalogOutNodeAmplitudeGet(deviceHandle, idxChannel, analogNode, pAmplitude)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeFrequencyGet(deviceHandle int32, idxChannel int, analogNode int, pFrequency *float64) int32 {
+	return b.fnAnalogOutNodeFrequencyGet(deviceHandle, idxChannel, analogNode, pFrequency)
+}
+
+func (b *libdwfBackend) FDwfAnalogOutNodeOffsetGet(deviceHandle int32, idxChannel int, analogNode int, pOffset *float64) int32 {
+	return b.fnAnalogOutNodeOffsetGet(deviceHandle, idxChannel, analogNode, pOffset)
+}
+
+func (b *libdwfBackend) FDwfAnalogInChannelCount(deviceHandle int32, channels *int) int32 {
+	return b.fnAnalogInChannelCount(deviceHandle, channels)
+}
+
+func (b *libdwfBackend) FDwfAnalogInConfigure(deviceHandle int32, fReconfigure int, fStart int) {
+	b.fnAnalogInConfigure(deviceHandle, fReconfigure, fStart)
+}
+
+func (b *libdwfBackend) FDwfAnalogInFrequencySet(deviceHandle int32, frequency float64) int32 {
+	return b.fnAnalogInFrequencySet(deviceHandle, frequency)
+}
+
+func (b *libdwfBackend) FDwfAnalogInBufferSizeSet(deviceHandle int32, bufferSize int) int32 {
+	return b.fnAnalogInBufferSizeSet(deviceHandle, bufferSize)
+}
+
+func (b *libdwfBackend) FDwfAnalogInChannelEnableSet(deviceHandle int32, idxChannel int, isEnabled int) int32 {
+	return b.fnAnalogInChannelEnableSet(deviceHandle, idxChannel, isEnabled)
+}
+
+func (b *libdwfBackend) FDwfAnalogInChannelRangeSet(deviceHandle int32, idxChannel int, volts float64) int32 {
+	return b.fnAnalogInChannelRangeSet(deviceHandle, idxChannel, volts)
+}
+
+func (b *libdwfBackend) FDwfAnalogInStatus(deviceHandle int32, readData int, pSTS *int) int32 {
+	return b.fnAnalogInStatus(deviceHandle, readData, pSTS)
+}
+
+func (b *libdwfBackend) FDwfAnalogInStatusData(deviceHandle int32, idxChannel int, rgdVolts *float64, cdData int) int32 {
+	return b.fnAnalogInStatusData(deviceHandle, idxChannel, rgdVolts, cdData)
+}
+
+func (b *libdwfBackend) FDwfDigitalInReset(deviceHandle int32) int32 {
+	return b.fnDigitalInReset(deviceHandle)
+}
+
+func (b *libdwfBackend) FDwfDigitalInConfigure(deviceHandle int32, fReconfigure int, fStart int) int32 {
+	return b.fnDigitalInConfigure(deviceHandle, fReconfigure, fStart)
+}
+
+func (b *libdwfBackend) FDwfDigitalInStatus(deviceHandle int32, fReadData int, pStatus *byte) int32 {
+	return b.fnDigitalInStatus(deviceHandle, fReadData, pStatus)
+}
+
+func (b *libdwfBackend) FDwfDigitalInStatusData(deviceHandle int32, rgBytes *byte, countOfDataBytes int) int32 {
+	return b.fnDigitalInStatusData(deviceHandle, rgBytes, countOfDataBytes)
+}
+
+func (b *libdwfBackend) FDwfDigitalInStatusRecord(deviceHandle int32, pDataAvailable *int, pDataLost *int, pDataCorrupt *int) int32 {
+	return b.fnDigitalInStatusRecord(deviceHandle, pDataAvailable, pDataLost, pDataCorrupt)
+}
+
+func (b *libdwfBackend) FDwfDigitalInInternalClockInfo(deviceHandle int32, phzFreq *float64) int32 {
+	return b.fnDigitalInInternalClockInfo(deviceHandle, phzFreq)
+}
+
+func (b *libdwfBackend) FDwfDigitalInDividerInfo(deviceHandle int32, pMax *int) int32 {
+	return b.fnDigitalInDividerInfo(deviceHandle, pMax)
+}
+
+func (b *libdwfBackend) FDwfDigitalInDividerSet(deviceHandle int32, divider int) int32 {
+	return b.fnDigitalInDividerSet(deviceHandle, divider)
+}
+
+func (b *libdwfBackend) FDwfDigitalInDividerGet(deviceHandle int32, pDivider *int) int32 {
+	return b.fnDigitalInDividerGet(deviceHandle, pDivider)
+}
+
+func (b *libdwfBackend) FDwfDigitalInSampleFormatSet(deviceHandle int32, bits int) int32 {
+	return b.fnDigitalInSampleFormatSet(deviceHandle, bits)
+}
+
+func (b *libdwfBackend) FDwfDigitalInBufferSizeInfo(deviceHandle int32, pMax *int) int32 {
+	return b.fnDigitalInBufferSizeInfo(deviceHandle, pMax)
+}
+
+func (b *libdwfBackend) FDwfDigitalInBufferSizeSet(deviceHandle int32, bufferSize int) int32 {
+	return b.fnDigitalInBufferSizeSet(deviceHandle, bufferSize)
+}
+
+func (b *libdwfBackend) FDwfDigitalInBufferSizeGet(deviceHandle int32, pBufferSize *int) int32 {
+	return b.fnDigitalInBufferSizeGet(deviceHandle, pBufferSize)
+}
+
+func (b *libdwfBackend) FDwfDigitalInAcquisitionModeSet(deviceHandle int32, acqMode int) int32 {
+	return b.fnDigitalInAcquisitionModeSet(deviceHandle, acqMode)
+}
+
+func (b *libdwfBackend) FDwfDigitalInTriggerSourceSet(deviceHandle int32, trigsrc uint8) int32 {
+	return b.fnDigitalInTriggerSourceSet(deviceHandle, trigsrc)
+}
+
+func (b *libdwfBackend) FDwfDigitalInTriggerSlopeSet(deviceHandle int32, slope int) int32 {
+	return b.fnDigitalInTriggerSlopeSet(deviceHandle, slope)
+}
+
+func (b *libdwfBackend) FDwfDigitalInTriggerPositionSet(deviceHandle int32, samples uint32) int32 {
+	return b.fnDigitalInTriggerPositionSet(deviceHandle, samples)
+}
+
+func (b *libdwfBackend) FDwfDigitalInTriggerPrefillSet(deviceHandle int32, samples uint32) int32 {
+	return b.fnDigitalInTriggerPrefillSet(deviceHandle, samples)
+}
+
+func (b *libdwfBackend) FDwfDigitalInTriggerAutoTimeoutSet(deviceHandle int32, secTimeout float64) int32 {
+	return b.fnDigitalInTriggerAutoTimeoutSet(deviceHandle, secTimeout)
+}
+
+func (b *libdwfBackend) FDwfDigitalInTriggerSet(deviceHandle int32, levelLow uint32, levelHigh uint32, edgeRise uint32, edgeFall uint32) int32 {
+	return b.fnDigitalInTriggerSet(deviceHandle, levelLow, levelHigh, edgeRise, edgeFall)
+}
@@ -0,0 +1,1036 @@
+package analogdiscovery
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const (
+	simulatedDeviceHandle = 1
+	simulatedDeviceId     = 3 // devidDiscovery2
+	simulatedDeviceRev    = 4
+	simulatedSerialNumber = "SN:210321ABCDEF"
+
+	simulatedAnalogOutChannels = 2
+	simulatedAnalogOutNodes    = 3
+	simulatedAnalogInChannels  = 2
+	simulatedAnalogInBufferMax = 8192
+	simulatedAnalogInFreqMax   = 100e6
+
+	simulatedDigitalInChannels   = 16
+	simulatedDigitalInClockHz    = 100e6
+	simulatedDigitalInBufferMax  = 4096
+	simulatedDigitalInDividerMax = 1 << 30
+
+	// Undriven digital inputs carry square waves whose half period grows with the
+	// channel number, so DIO 0 toggles every 10 us, DIO 1 every 20 us and so on.
+	simulatedStimulusHalfPeriod = 10 * time.Microsecond
+)
+
+// WaveForms SDK error codes reported through FDwfGetLastError
+const (
+	dwfercUnknownError      = 1
+	dwfercAlreadyOpened     = 3
+	dwfercNotSupported      = 4
+	dwfercInvalidParameter0 = 0x10
+)
+
+// WaveForms SDK instrument states reported through the status functions
+const (
+	dwfStateReady     = 0
+	dwfStateArmed     = 1
+	dwfStateTriggered = 3
+)
+
+// WaveForms SDK analog out functions (FUNC)
+const (
+	dwfFuncDC        = 0
+	dwfFuncSine      = 1
+	dwfFuncSquare    = 2
+	dwfFuncTriangle  = 3
+	dwfFuncRampUp    = 4
+	dwfFuncRampDown  = 5
+	dwfFuncNoise     = 6
+	dwfFuncPulse     = 7
+	dwfFuncTrapezium = 8
+	dwfFuncSinePower = 9
+)
+
+type simulatedAnalogOutNode struct {
+	enabled   int
+	function  uint16
+	frequency float64
+	amplitude float64
+	offset    float64
+	symmetry  float64
+}
+
+type simulatedAnalogOutChannel struct {
+	nodes     [simulatedAnalogOutNodes]simulatedAnalogOutNode
+	running   bool
+	startedAt time.Time
+}
+
+type simulatedDigitalIn struct {
+	sampleBits         int
+	divider            int
+	bufferSize         int
+	acquisitionMode    int
+	triggerSource      uint8
+	triggerSlope       int
+	triggerPosition    uint32
+	triggerPrefill     uint32
+	triggerAutoTimeout float64
+	levelLow           uint32
+	levelHigh          uint32
+	edgeRise           uint32
+	edgeFall           uint32
+
+	armed        bool
+	hasTrigger   bool
+	triggerAt    time.Duration
+	captureStart time.Duration
+	captureDone  time.Duration
+	samples      []uint16
+}
+
+// SimulatedBackend is an in-process stand-in for an Analog Discovery 2. It keeps the
+// wavegen, scope, digital IO and logic analyzer state the device code relies on, loops
+// the wavegen channels back into the scope channels and feeds undriven DIO lines with
+// deterministic square waves, so the handlers can run without libdwf or hardware.
+type SimulatedBackend struct {
+	mu    sync.Mutex
+	epoch time.Time
+
+	isOpen       bool
+	lastError    int32
+	lastErrorMsg string
+
+	dioOutputEnable uint16
+	dioOutput       uint16
+
+	analogOut [simulatedAnalogOutChannels]simulatedAnalogOutChannel
+
+	analogInFrequency  float64
+	analogInBufferSize int
+	analogInEnabled    [simulatedAnalogInChannels]bool
+	analogInRange      [simulatedAnalogInChannels]float64
+	analogInRunning    bool
+	analogInStartedAt  time.Time
+
+	digitalIn simulatedDigitalIn
+}
+
+func NewSimulatedBackend() *SimulatedBackend {
+	s := &SimulatedBackend{
+		epoch:              time.Now(),
+		analogInFrequency:  20e6,
+		analogInBufferSize: simulatedAnalogInBufferMax,
+	}
+	for ch := range s.analogOut {
+		for node := range s.analogOut[ch].nodes {
+			s.analogOut[ch].nodes[node] = simulatedAnalogOutNode{
+				frequency: 1000,
+				amplitude: 1,
+				symmetry:  50,
+			}
+		}
+		s.analogOut[ch].nodes[0].enabled = 1
+	}
+	for ch := range s.analogInEnabled {
+		s.analogInEnabled[ch] = true
+		s.analogInRange[ch] = 5
+	}
+	s.resetDigitalIn()
+	return s
+}
+
+func (s *SimulatedBackend) fail(code int32, message string) int32 {
+	s.lastError = code
+	s.lastErrorMsg = message
+	return 0
+}
+
+func (s *SimulatedBackend) checkHandle(deviceHandle int32) bool {
+	if !s.isOpen || deviceHandle != simulatedDeviceHandle {
+		s.fail(dwfercInvalidParameter0, "Invalid device handle provided")
+		return false
+	}
+	return true
+}
+
+func (s *SimulatedBackend) analogOutNode(deviceHandle int32, idxChannel int, analogNode int) (*simulatedAnalogOutNode, bool) {
+	if !s.checkHandle(deviceHandle) {
+		return nil, false
+	}
+	if idxChannel < 0 || idxChannel >= simulatedAnalogOutChannels {
+		s.fail(dwfercInvalidParameter0+1, "Invalid channel index provided")
+		return nil, false
+	}
+	if analogNode < 0 || analogNode >= simulatedAnalogOutNodes {
+		s.fail(dwfercInvalidParameter0+2, "Invalid node index provided")
+		return nil, false
+	}
+	return &s.analogOut[idxChannel].nodes[analogNode], true
+}
+
+// ----- DEVICE -----
+
+func (s *SimulatedBackend) FDwfEnum(deviceType int32, count *int32) {
+	if count != nil {
+		*count = 1
+	}
+}
+
+func (s *SimulatedBackend) FDwfDeviceConfigOpen(index int32, auto int32, deviceHandle *int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if deviceHandle == nil {
+		return
+	}
+	*deviceHandle = 0
+	if index != 0 {
+		s.fail(dwfercInvalidParameter0, "Invalid device index provided")
+		return
+	}
+	if s.isOpen {
+		s.fail(dwfercAlreadyOpened, "Device already opened")
+		return
+	}
+	s.isOpen = true
+	*deviceHandle = simulatedDeviceHandle
+}
+
+func (s *SimulatedBackend) FDwfEnumDeviceType(index int32, deviceId *int32, deviceRev *int32) {
+	if index != 0 {
+		return
+	}
+	if deviceId != nil {
+		*deviceId = simulatedDeviceId
+	}
+	if deviceRev != nil {
+		*deviceRev = simulatedDeviceRev
+	}
+}
+
+// FDwfEnumSN fills a 32 byte buffer like the SDK does
+func (s *SimulatedBackend) FDwfEnumSN(index int32, serialNumber *byte) {
+	if index != 0 || serialNumber == nil {
+		return
+	}
+	buf := unsafe.Slice(serialNumber, 32)
+	n := copy(buf[:len(buf)-1], simulatedSerialNumber)
+	buf[n] = 0
+}
+
+func (s *SimulatedBackend) FDwfGetLastError(errorNumber *int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if errorNumber != nil {
+		*errorNumber = s.lastError
+	}
+}
+
+// FDwfGetLastErrorMsg fills a 512 byte buffer like the SDK does
+func (s *SimulatedBackend) FDwfGetLastErrorMsg(errorMessage *byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if errorMessage == nil {
+		return
+	}
+	buf := unsafe.Slice(errorMessage, 512)
+	n := copy(buf[:len(buf)-1], s.lastErrorMsg)
+	buf[n] = 0
+}
+
+func (s *SimulatedBackend) FDwfDeviceClose(deviceHandle int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if deviceHandle == simulatedDeviceHandle {
+		s.isOpen = false
+	}
+}
+
+// ----- DIGITAL IO -----
+
+func (s *SimulatedBackend) FDwfDigitalIOOutputEnableGet(deviceHandle int32, mask *uint16) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if mask != nil {
+		*mask = s.dioOutputEnable
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalIOOutputEnableSet(deviceHandle int32, mask uint16) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.dioOutputEnable = mask
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalIOOutputGet(deviceHandle int32, value *uint16) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if value != nil {
+		*value = s.dioOutput
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalIOOutputSet(deviceHandle int32, value uint16) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.dioOutput = value
+	return 1
+}
+
+// Outputs are applied as soon as they are set (the SDK's auto-configure default),
+// so configuring only validates the handle.
+func (s *SimulatedBackend) FDwfDigitalIOConfigure(deviceHandle int32) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	return 1
+}
+
+// digitalInputAt returns the 16-bit DIO bus value at the given time since the epoch
+func (s *SimulatedBackend) digitalInputAt(t time.Duration) uint16 {
+	var value uint16
+	for ch := 0; ch < simulatedDigitalInChannels; ch++ {
+		bit := uint16(1) << ch
+		if s.dioOutputEnable&bit != 0 {
+			value |= s.dioOutput & bit
+			continue
+		}
+		halfPeriod := simulatedStimulusHalfPeriod * time.Duration(ch+1)
+		if (t/halfPeriod)%2 == 1 {
+			value |= bit
+		}
+	}
+	return value
+}
+
+// ----- ANALOG OUT (WAVEFORM GENERATOR) -----
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeEnableSet(deviceHandle int32, idxChannel int, analogNode int, isEnabled int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if isEnabled != 0 {
+		node.enabled = 1
+	} else {
+		node.enabled = 0
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeFunctionSet(deviceHandle int32, idxChannel int, analogNode int, function uint16) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if function > dwfFuncSinePower {
+		return s.fail(dwfercNotSupported, "Function not supported")
+	}
+	node.function = function
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeFrequencySet(deviceHandle int32, idxChannel int, analogNode int, frequency float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	node.frequency = math.Min(math.Max(frequency, 0), 12.5e6)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeAmplitudeSet(deviceHandle int32, idxChannel int, analogNode int, amplitude float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	node.amplitude = math.Min(math.Max(amplitude, -5), 5)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeOffsetSet(deviceHandle int32, idxChannel int, analogNode int, offset float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	node.offset = math.Min(math.Max(offset, -5), 5)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeSymmetrySet(deviceHandle int32, idxChannel int, analogNode int, percSymmetry float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	node.symmetry = math.Min(math.Max(percSymmetry, 0), 100)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutConfigure(deviceHandle int32, idxChannel int, fStart int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.analogOutNode(deviceHandle, idxChannel, 0); !ok {
+		return
+	}
+	switch fStart {
+	case 0:
+		s.analogOut[idxChannel].running = false
+	case 1:
+		s.analogOut[idxChannel].running = true
+		s.analogOut[idxChannel].startedAt = time.Now()
+	}
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeSymmetryGet(deviceHandle int32, idxChannel int, analogNode int, pSymmetry *float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if pSymmetry != nil {
+		*pSymmetry = node.symmetry
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeEnableGet(deviceHandle int32, idxChannel int, analogNode int, isEnabled *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if isEnabled != nil {
+		*isEnabled = node.enabled
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeFunctionGet(deviceHandle int32, idxChannel int, analogNode int, funcName *uint16) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if funcName != nil {
+		*funcName = node.function
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeAmplitudeGet(deviceHandle int32, idxChannel int, analogNode int, pAmplitude *float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if pAmplitude != nil {
+		*pAmplitude = node.amplitude
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeFrequencyGet(deviceHandle int32, idxChannel int, analogNode int, pFrequency *float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if pFrequency != nil {
+		*pFrequency = node.frequency
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogOutNodeOffsetGet(deviceHandle int32, idxChannel int, analogNode int, pOffset *float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, ok := s.analogOutNode(deviceHandle, idxChannel, analogNode)
+	if !ok {
+		return 0
+	}
+	if pOffset != nil {
+		*pOffset = node.offset
+	}
+	return 1
+}
+
+// voltageAt evaluates the carrier waveform for a phase in the range [0, 1)
+func (n simulatedAnalogOutNode) voltageAt(phase float64) float64 {
+	symmetry := n.symmetry / 100
+	switch n.function {
+	case dwfFuncSine:
+		return n.offset + n.amplitude*math.Sin(2*math.Pi*phase)
+	case dwfFuncSquare:
+		if phase < symmetry {
+			return n.offset + n.amplitude
+		}
+		return n.offset - n.amplitude
+	case dwfFuncTriangle, dwfFuncTrapezium:
+		if phase < symmetry {
+			return n.offset - n.amplitude + 2*n.amplitude*phase/symmetry
+		}
+		return n.offset + n.amplitude - 2*n.amplitude*(phase-symmetry)/(1-symmetry)
+	case dwfFuncRampUp:
+		return n.offset - n.amplitude + 2*n.amplitude*phase
+	case dwfFuncRampDown:
+		return n.offset + n.amplitude - 2*n.amplitude*phase
+	case dwfFuncNoise:
+		return n.offset + n.amplitude*math.Sin(2*math.Pi*phase*7919)
+	case dwfFuncPulse:
+		if phase < symmetry {
+			return n.offset + n.amplitude
+		}
+		return n.offset
+	case dwfFuncSinePower:
+		return n.offset + n.amplitude*math.Pow(math.Sin(2*math.Pi*phase), 3)
+	default:
+		return n.offset
+	}
+}
+
+// analogOutVoltageAt returns what the wavegen channel drives at the given time.
+// A stopped channel or a disabled carrier outputs 0 V.
+func (s *SimulatedBackend) analogOutVoltageAt(channel int, t time.Time) float64 {
+	out := s.analogOut[channel]
+	carrier := out.nodes[0]
+	if !out.running || carrier.enabled == 0 {
+		return 0
+	}
+	elapsed := t.Sub(out.startedAt).Seconds()
+	_, phase := math.Modf(elapsed * carrier.frequency)
+	return carrier.voltageAt(phase)
+}
+
+// ----- ANALOG IN (OSCILLOSCOPE) -----
+
+func (s *SimulatedBackend) FDwfAnalogInChannelCount(deviceHandle int32, channels *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if channels != nil {
+		*channels = simulatedAnalogInChannels
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogInConfigure(deviceHandle int32, fReconfigure int, fStart int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return
+	}
+	if fStart != 0 {
+		s.analogInRunning = true
+		s.analogInStartedAt = time.Now()
+	} else {
+		s.analogInRunning = false
+	}
+}
+
+func (s *SimulatedBackend) FDwfAnalogInFrequencySet(deviceHandle int32, frequency float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if frequency <= 0 {
+		return s.fail(dwfercInvalidParameter0+1, "Invalid frequency provided")
+	}
+	s.analogInFrequency = math.Min(frequency, simulatedAnalogInFreqMax)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogInBufferSizeSet(deviceHandle int32, bufferSize int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.analogInBufferSize = min(max(bufferSize, 16), simulatedAnalogInBufferMax)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogInChannelEnableSet(deviceHandle int32, idxChannel int, isEnabled int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if idxChannel < 0 || idxChannel >= simulatedAnalogInChannels {
+		return s.fail(dwfercInvalidParameter0+1, "Invalid channel index provided")
+	}
+	s.analogInEnabled[idxChannel] = isEnabled != 0
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogInChannelRangeSet(deviceHandle int32, idxChannel int, volts float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if idxChannel < 0 || idxChannel >= simulatedAnalogInChannels {
+		return s.fail(dwfercInvalidParameter0+1, "Invalid channel index provided")
+	}
+	// AD2 has a 5 V and a 50 V peak-to-peak range
+	if volts > 5 {
+		s.analogInRange[idxChannel] = 50
+	} else {
+		s.analogInRange[idxChannel] = 5
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfAnalogInStatus(deviceHandle int32, readData int, pSTS *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	status := dwfStateReady
+	if s.analogInRunning {
+		acquisition := time.Duration(float64(s.analogInBufferSize) / s.analogInFrequency * float64(time.Second))
+		if time.Since(s.analogInStartedAt) >= acquisition {
+			status = dwfStateDone
+		} else {
+			status = dwfStateTriggered
+		}
+	}
+	if pSTS != nil {
+		*pSTS = status
+	}
+	return 1
+}
+
+// FDwfAnalogInStatusData returns the most recent cdData samples of the channel,
+// which is wired back to the wavegen channel with the same index.
+func (s *SimulatedBackend) FDwfAnalogInStatusData(deviceHandle int32, idxChannel int, rgdVolts *float64, cdData int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if idxChannel < 0 || idxChannel >= simulatedAnalogInChannels {
+		return s.fail(dwfercInvalidParameter0+1, "Invalid channel index provided")
+	}
+	if rgdVolts == nil || cdData <= 0 {
+		return s.fail(dwfercInvalidParameter0+2, "Invalid buffer provided")
+	}
+
+	samples := unsafe.Slice(rgdVolts, cdData)
+	if !s.analogInRunning || !s.analogInEnabled[idxChannel] {
+		for i := range samples {
+			samples[i] = 0
+		}
+		return 1
+	}
+
+	limit := s.analogInRange[idxChannel] / 2
+	period := time.Duration(float64(time.Second) / s.analogInFrequency)
+	now := time.Now()
+	for i := range samples {
+		t := now.Add(-time.Duration(cdData-i) * period)
+		samples[i] = math.Min(math.Max(s.analogOutVoltageAt(idxChannel, t), -limit), limit)
+	}
+	return 1
+}
+
+// ----- DIGITAL IN (LOGIC ANALYZER) -----
+
+func (s *SimulatedBackend) resetDigitalIn() {
+	s.digitalIn = simulatedDigitalIn{
+		sampleBits: 32,
+		divider:    1,
+		bufferSize: simulatedDigitalInBufferMax,
+	}
+}
+
+func (s *SimulatedBackend) FDwfDigitalInReset(deviceHandle int32) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.resetDigitalIn()
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInConfigure(deviceHandle int32, fReconfigure int, fStart int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+
+	di := &s.digitalIn
+	if fStart == 0 {
+		di.armed = false
+		return 1
+	}
+
+	di.armed = true
+	di.samples = nil
+	armedAt := time.Since(s.epoch)
+	samplePeriod := float64(di.divider) / simulatedDigitalInClockHz * float64(time.Second)
+	captureLength := time.Duration(float64(di.bufferSize) * samplePeriod)
+
+	if di.triggerSource == dwfTrigSrcNone {
+		di.hasTrigger = true
+		di.triggerAt = armedAt
+		di.captureStart = armedAt
+		di.captureDone = armedAt + captureLength
+		return 1
+	}
+
+	di.triggerAt, di.hasTrigger = s.nextDigitalEdge(armedAt, di.edgeRise, di.edgeFall)
+	if !di.hasTrigger && di.triggerAutoTimeout > 0 {
+		di.hasTrigger = true
+		di.triggerAt = armedAt + time.Duration(di.triggerAutoTimeout*float64(time.Second))
+	}
+	if di.hasTrigger {
+		// The trigger position is the number of samples kept after the trigger
+		afterTrigger := min(int(di.triggerPosition), di.bufferSize)
+		di.captureStart = di.triggerAt - time.Duration(float64(di.bufferSize-afterTrigger)*samplePeriod)
+		di.captureDone = di.captureStart + captureLength
+	}
+	return 1
+}
+
+// nextDigitalEdge finds the first matching edge on an undriven DIO line at or after from.
+// Driven lines hold a constant level, so an edge trigger on them never fires.
+func (s *SimulatedBackend) nextDigitalEdge(from time.Duration, edgeRise uint32, edgeFall uint32) (time.Duration, bool) {
+	var best time.Duration
+	found := false
+	for ch := 0; ch < simulatedDigitalInChannels; ch++ {
+		bit := uint32(1) << ch
+		if (edgeRise|edgeFall)&bit == 0 || uint32(s.dioOutputEnable)&bit != 0 {
+			continue
+		}
+
+		halfPeriod := simulatedStimulusHalfPeriod * time.Duration(ch+1)
+		// Toggle k happens at k*halfPeriod and leaves the line at level k%2
+		k := int64((from + halfPeriod - 1) / halfPeriod)
+		if k == 0 {
+			k = 1
+		}
+		for ; ; k++ {
+			rising := k%2 == 1
+			if (rising && edgeRise&bit != 0) || (!rising && edgeFall&bit != 0) {
+				break
+			}
+		}
+		at := time.Duration(k) * halfPeriod
+		if !found || at < best {
+			best = at
+			found = true
+		}
+	}
+	return best, found
+}
+
+func (s *SimulatedBackend) FDwfDigitalInStatus(deviceHandle int32, fReadData int, pStatus *byte) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+
+	di := &s.digitalIn
+	status := byte(dwfStateReady)
+	now := time.Since(s.epoch)
+	switch {
+	case di.samples != nil:
+		status = dwfStateDone
+	case !di.armed:
+		status = dwfStateReady
+	case di.hasTrigger && now >= di.captureDone:
+		s.captureDigitalIn()
+		status = dwfStateDone
+	case di.hasTrigger && now >= di.triggerAt:
+		status = dwfStateTriggered
+	default:
+		status = dwfStateArmed
+	}
+	if pStatus != nil {
+		*pStatus = status
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) captureDigitalIn() {
+	di := &s.digitalIn
+	samplePeriod := float64(di.divider) / simulatedDigitalInClockHz * float64(time.Second)
+	di.samples = make([]uint16, di.bufferSize)
+	for i := range di.samples {
+		di.samples[i] = s.digitalInputAt(di.captureStart + time.Duration(float64(i)*samplePeriod))
+	}
+	di.armed = false
+}
+
+func (s *SimulatedBackend) FDwfDigitalInStatusData(deviceHandle int32, rgBytes *byte, countOfDataBytes int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	di := &s.digitalIn
+	if di.samples == nil {
+		return s.fail(dwfercUnknownError, "No acquisition data available")
+	}
+	if rgBytes == nil || countOfDataBytes <= 0 {
+		return s.fail(dwfercInvalidParameter0+1, "Invalid buffer provided")
+	}
+
+	dest := unsafe.Slice(rgBytes, countOfDataBytes)
+	sampleBytes := di.sampleBits / 8
+	for i, sample := range di.samples {
+		offset := i * sampleBytes
+		if offset+sampleBytes > len(dest) {
+			break
+		}
+		switch sampleBytes {
+		case 1:
+			dest[offset] = byte(sample)
+		case 2:
+			binary.LittleEndian.PutUint16(dest[offset:], sample)
+		default:
+			binary.LittleEndian.PutUint32(dest[offset:], uint32(sample))
+		}
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInStatusRecord(deviceHandle int32, pDataAvailable *int, pDataLost *int, pDataCorrupt *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if pDataAvailable != nil {
+		*pDataAvailable = len(s.digitalIn.samples)
+	}
+	if pDataLost != nil {
+		*pDataLost = 0
+	}
+	if pDataCorrupt != nil {
+		*pDataCorrupt = 0
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInInternalClockInfo(deviceHandle int32, phzFreq *float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if phzFreq != nil {
+		*phzFreq = simulatedDigitalInClockHz
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInDividerInfo(deviceHandle int32, pMax *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if pMax != nil {
+		*pMax = simulatedDigitalInDividerMax
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInDividerSet(deviceHandle int32, divider int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.digitalIn.divider = min(max(divider, 1), simulatedDigitalInDividerMax)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInDividerGet(deviceHandle int32, pDivider *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if pDivider != nil {
+		*pDivider = s.digitalIn.divider
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInSampleFormatSet(deviceHandle int32, bits int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if bits != 8 && bits != 16 && bits != 32 {
+		return s.fail(dwfercInvalidParameter0+1, "Invalid sample format provided")
+	}
+	s.digitalIn.sampleBits = bits
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInBufferSizeInfo(deviceHandle int32, pMax *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if pMax != nil {
+		*pMax = simulatedDigitalInBufferMax
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInBufferSizeSet(deviceHandle int32, bufferSize int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.digitalIn.bufferSize = min(max(bufferSize, 1), simulatedDigitalInBufferMax)
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInBufferSizeGet(deviceHandle int32, pBufferSize *int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if pBufferSize != nil {
+		*pBufferSize = s.digitalIn.bufferSize
+	}
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInAcquisitionModeSet(deviceHandle int32, acqMode int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if acqMode != dwfAcqModeSingle {
+		return s.fail(dwfercNotSupported, "Only single acquisition mode is simulated")
+	}
+	s.digitalIn.acquisitionMode = acqMode
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInTriggerSourceSet(deviceHandle int32, trigsrc uint8) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	if trigsrc != dwfTrigSrcNone && trigsrc != dwfTrigSrcDetectorDigitalIn {
+		return s.fail(dwfercNotSupported, "Trigger source not supported")
+	}
+	s.digitalIn.triggerSource = trigsrc
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInTriggerSlopeSet(deviceHandle int32, slope int) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.digitalIn.triggerSlope = slope
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInTriggerPositionSet(deviceHandle int32, samples uint32) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.digitalIn.triggerPosition = samples
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInTriggerPrefillSet(deviceHandle int32, samples uint32) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.digitalIn.triggerPrefill = samples
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInTriggerAutoTimeoutSet(deviceHandle int32, secTimeout float64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.digitalIn.triggerAutoTimeout = secTimeout
+	return 1
+}
+
+func (s *SimulatedBackend) FDwfDigitalInTriggerSet(deviceHandle int32, levelLow uint32, levelHigh uint32, edgeRise uint32, edgeFall uint32) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkHandle(deviceHandle) {
+		return 0
+	}
+	s.digitalIn.levelLow = levelLow
+	s.digitalIn.levelHigh = levelHigh
+	s.digitalIn.edgeRise = edgeRise
+	s.digitalIn.edgeFall = edgeFall
+	return 1
+}
@@ -0,0 +1,112 @@
+package analogdiscovery
+
+import (
+	"slices"
+	"testing"
+)
+
+func newSimulatedDevice(t *testing.T) *AnalogDiscoveryDevice {
+	t.Helper()
+	device, err := CreateDevice(NewSimulatedBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(device.Close)
+	return device
+}
+
+// The scope channels are looped back to the wavegen, so a running sine shows
+// up in the captured samples while a stopped wavegen reads as 0 V
+func TestSimulatedScopeReadsWavegen(t *testing.T) {
+	device := newSimulatedDevice(t)
+
+	samples, times, err := device.ReadScopeValues(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 600 || len(times) != 600 {
+		t.Fatalf("got %d samples and %d timestamps, want 600 each", len(samples), len(times))
+	}
+	if slices.Max(samples) != 0 || slices.Min(samples) != 0 {
+		t.Errorf("stopped wavegen reads %v..%v V, want 0 V", slices.Min(samples), slices.Max(samples))
+	}
+
+	const node = "AnalogOutNodeCarrier"
+	if err := device.SetAnalogOutNodeFunction(0, node, "sine"); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.SetAnalogOutFrequency(0, node, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.SetAnalogOutAmplitude(0, node, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.GenerateWaveform(0, node, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sine is shifted to 0..2 V and the 600 us window covers most of a period
+	samples, _, err = device.ReadScopeValues(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, high := slices.Min(samples), slices.Max(samples)
+	if low < -0.01 || high > 2.01 {
+		t.Errorf("samples span %v..%v V, want them within 0..2 V", low, high)
+	}
+	if high-low < 1 {
+		t.Errorf("samples span %v..%v V, want a swing of at least 1 V", low, high)
+	}
+}
+
+// A DIO pin driven through SetPinState holds its level in a capture, while an
+// undriven pin carries the simulator's square wave
+func TestSimulatedLogicCaptureSeesPinState(t *testing.T) {
+	device := newSimulatedDevice(t)
+
+	if err := device.SetPinMode(0, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.SetPinState(0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := device.CaptureLogicTransitions(LogicCaptureRequest{
+		MeasurementTimeUs: 200,
+		Channels:          []int{0, 1},
+		Trigger:           LogicCaptureTrigger{Type: "immediate"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Triggered {
+		t.Fatalf("capture did not trigger: %v", response.Warnings)
+	}
+	if len(response.Channels) != 2 {
+		t.Fatalf("got %d channels, want 2", len(response.Channels))
+	}
+
+	driven := response.Channels[0].Transitions
+	if len(driven) != 1 || driven[0].Value != 1 {
+		t.Errorf("driven DIO 0 transitions = %v, want a single high level", driven)
+	}
+	// DIO 1 toggles every 20 us, so a 200 us window holds several edges
+	if undriven := response.Channels[1].Transitions; len(undriven) < 5 {
+		t.Errorf("undriven DIO 1 has %d transitions, want at least 5", len(undriven))
+	}
+
+	if err := device.SetPinState(0, false); err != nil {
+		t.Fatal(err)
+	}
+	response, err = device.CaptureLogicTransitions(LogicCaptureRequest{
+		MeasurementTimeUs: 200,
+		Channels:          []int{0},
+		Trigger:           LogicCaptureTrigger{Type: "immediate"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if driven := response.Channels[0].Transitions; len(driven) != 1 || driven[0].Value != 0 {
+		t.Errorf("driven DIO 0 transitions after clearing = %v, want a single low level", driven)
+	}
+}
@@ -0,0 +1,83 @@
+package analogdiscovery
+
+import "fmt"
+
+// Backend is the subset of the WaveForms SDK (libdwf) used by AnalogDiscoveryDevice.
+// Method names and signatures mirror the FDwf* C functions, so the real binding is a
+// thin purego shim and other implementations can be swapped in without touching callers.
+type Backend interface {
+	FDwfEnum(deviceType int32, count *int32)
+	FDwfDeviceConfigOpen(index int32, auto int32, deviceHandle *int32)
+	FDwfEnumDeviceType(index int32, deviceId *int32, deviceRev *int32)
+	FDwfEnumSN(index int32, serialNumber *byte)
+	FDwfGetLastError(errorNumber *int32)
+	FDwfGetLastErrorMsg(errorMessage *byte)
+	FDwfDeviceClose(deviceHandle int32)
+
+	FDwfDigitalIOOutputEnableGet(deviceHandle int32, mask *uint16) int32
+	FDwfDigitalIOOutputEnableSet(deviceHandle int32, mask uint16) int32
+	FDwfDigitalIOOutputGet(deviceHandle int32, value *uint16) int32
+	FDwfDigitalIOOutputSet(deviceHandle int32, value uint16) int32
+	FDwfDigitalIOConfigure(deviceHandle int32) int32
+
+	FDwfAnalogOutNodeEnableSet(deviceHandle int32, idxChannel int, analogNode int, isEnabled int) int32
+	FDwfAnalogOutNodeFunctionSet(deviceHandle int32, idxChannel int, analogNode int, function uint16) int32
+	FDwfAnalogOutNodeFrequencySet(deviceHandle int32, idxChannel int, analogNode int, frequency float64) int32
+	FDwfAnalogOutNodeAmplitudeSet(deviceHandle int32, idxChannel int, analogNode int, amplitude float64) int32
+	FDwfAnalogOutNodeOffsetSet(deviceHandle int32, idxChannel int, analogNode int, offset float64) int32
+	FDwfAnalogOutNodeSymmetrySet(deviceHandle int32, idxChannel int, analogNode int, percSymmetry float64) int32
+	FDwfAnalogOutConfigure(deviceHandle int32, idxChannel int, fStart int)
+	FDwfAnalogOutNodeSymmetryGet(deviceHandle int32, idxChannel int, analogNode int, pSymmetry *float64) int32
+	FDwfAnalogOutNodeEnableGet(deviceHandle int32, idxChannel int, analogNode int, isEnabled *int) int32
+	FDwfAnalogOutNodeFunctionGet(deviceHandle int32, idxChannel int, analogNode int, funcName *uint16) int32
+	FDwfAnalogOutNodeAmplitudeGet(deviceHandle int32, idxChannel int, analogNode int, pAmplitude *float64) int32
+	FDwfAnalogOutNodeFrequencyGet(deviceHandle int32, idxChannel int, analogNode int, pFrequency *float64) int32
+	FDwfAnalogOutNodeOffsetGet(deviceHandle int32, idxChannel int, analogNode int, pOffset *float64) int32
+
+	FDwfAnalogInChannelCount(deviceHandle int32, channels *int) int32
+	FDwfAnalogInConfigure(deviceHandle int32, fReconfigure int, fStart int)
+	FDwfAnalogInFrequencySet(deviceHandle int32, frequency float64) int32
+	FDwfAnalogInBufferSizeSet(deviceHandle int32, bufferSize int) int32
+	FDwfAnalogInChannelEnableSet(deviceHandle int32, idxChannel int, isEnabled int) int32
+	FDwfAnalogInChannelRangeSet(deviceHandle int32, idxChannel int, volts float64) int32
+	FDwfAnalogInStatus(deviceHandle int32, readData int, pSTS *int) int32
+	FDwfAnalogInStatusData(deviceHandle int32, idxChannel int, rgdVolts *float64, cdData int) int32
+
+	FDwfDigitalInReset(deviceHandle int32) int32
+	FDwfDigitalInConfigure(deviceHandle int32, fReconfigure int, fStart int) int32
+	FDwfDigitalInStatus(deviceHandle int32, fReadData int, pStatus *byte) int32
+	FDwfDigitalInStatusData(deviceHandle int32, rgBytes *byte, countOfDataBytes int) int32
+	FDwfDigitalInStatusRecord(deviceHandle int32, pDataAvailable *int, pDataLost *int, pDataCorrupt *int) int32
+	FDwfDigitalInInternalClockInfo(deviceHandle int32, phzFreq *float64) int32
+	FDwfDigitalInDividerInfo(deviceHandle int32, pMax *int) int32
+	FDwfDigitalInDividerSet(deviceHandle int32, divider int) int32
+	FDwfDigitalInDividerGet(deviceHandle int32, pDivider *int) int32
+	FDwfDigitalInSampleFormatSet(deviceHandle int32, bits int) int32
+	FDwfDigitalInBufferSizeInfo(deviceHandle int32, pMax *int) int32
+	FDwfDigitalInBufferSizeSet(deviceHandle int32, bufferSize int) int32
+	FDwfDigitalInBufferSizeGet(deviceHandle int32, pBufferSize *int) int32
+	FDwfDigitalInAcquisitionModeSet(deviceHandle int32, acqMode int) int32
+	FDwfDigitalInTriggerSourceSet(deviceHandle int32, trigsrc uint8) int32
+	FDwfDigitalInTriggerSlopeSet(deviceHandle int32, slope int) int32
+	FDwfDigitalInTriggerPositionSet(deviceHandle int32, samples uint32) int32
+	FDwfDigitalInTriggerPrefillSet(deviceHandle int32, samples uint32) int32
+	FDwfDigitalInTriggerAutoTimeoutSet(deviceHandle int32, secTimeout float64) int32
+	FDwfDigitalInTriggerSet(deviceHandle int32, levelLow uint32, levelHigh uint32, edgeRise uint32, edgeFall uint32) int32
+}
+
+const (
+	BackendLibdwf    = "libdwf"
+	BackendSimulated = "simulated"
+)
+
+// NewBackend returns the instrument backend registered under the given name
+func NewBackend(name string) (Backend, error) {
+	switch name {
+	case "", BackendLibdwf:
+		return NewLibdwfBackend()
+	case BackendSimulated:
+		return NewSimulatedBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown analog discovery backend: %s", name)
+	}
+}
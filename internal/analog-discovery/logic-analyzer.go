@@ -11,8 +11,6 @@ import (
 	"strings"
 	"time"
 	"unsafe"
-
-	"github.com/ebitengine/purego"
 )
 
 const (
@@ -41,29 +39,6 @@ const (
 	dwfStateDone = 2
 )
 
-var (
-	FDwfDigitalInReset                 func(deviceHandle int32) int32
-	FDwfDigitalInConfigure             func(deviceHandle int32, fReconfigure int, fStart int) int32
-	FDwfDigitalInStatus                func(deviceHandle int32, fReadData int, pStatus *byte) int32
-	FDwfDigitalInStatusData            func(deviceHandle int32, rgBytes *byte, countOfDataBytes int) int32
-	FDwfDigitalInStatusRecord          func(deviceHandle int32, pDataAvailable *int, pDataLost *int, pDataCorrupt *int) int32
-	FDwfDigitalInInternalClockInfo     func(deviceHandle int32, phzFreq *float64) int32
-	FDwfDigitalInDividerInfo           func(deviceHandle int32, pMax *int) int32
-	FDwfDigitalInDividerSet            func(deviceHandle int32, divider int) int32
-	FDwfDigitalInDividerGet            func(deviceHandle int32, pDivider *int) int32
-	FDwfDigitalInSampleFormatSet       func(deviceHandle int32, bits int) int32
-	FDwfDigitalInBufferSizeInfo        func(deviceHandle int32, pMax *int) int32
-	FDwfDigitalInBufferSizeSet         func(deviceHandle int32, bufferSize int) int32
-	FDwfDigitalInBufferSizeGet         func(deviceHandle int32, pBufferSize *int) int32
-	FDwfDigitalInAcquisitionModeSet    func(deviceHandle int32, acqMode int) int32
-	FDwfDigitalInTriggerSourceSet      func(deviceHandle int32, trigsrc uint8) int32
-	FDwfDigitalInTriggerSlopeSet       func(deviceHandle int32, slope int) int32
-	FDwfDigitalInTriggerPositionSet    func(deviceHandle int32, samples uint32) int32
-	FDwfDigitalInTriggerPrefillSet     func(deviceHandle int32, samples uint32) int32
-	FDwfDigitalInTriggerAutoTimeoutSet func(deviceHandle int32, secTimeout float64) int32
-	FDwfDigitalInTriggerSet            func(deviceHandle int32, levelLow uint32, levelHigh uint32, edgeRise uint32, edgeFall uint32) int32
-)
-
 var ErrLogicCaptureBusy = errors.New("logic analyzer capture already in progress")
 
 type ValidationError struct {
@@ -138,29 +113,6 @@ type logicAnalyzerConfig struct {
 	BufferSizeSamples   int
 }
 
-func registerDigitalInFunctions() {
-	purego.RegisterLibFunc(&FDwfDigitalInReset, dwf, "FDwfDigitalInReset")
-	purego.RegisterLibFunc(&FDwfDigitalInConfigure, dwf, "FDwfDigitalInConfigure")
-	purego.RegisterLibFunc(&FDwfDigitalInStatus, dwf, "FDwfDigitalInStatus")
-	purego.RegisterLibFunc(&FDwfDigitalInStatusData, dwf, "FDwfDigitalInStatusData")
-	purego.RegisterLibFunc(&FDwfDigitalInStatusRecord, dwf, "FDwfDigitalInStatusRecord")
-	purego.RegisterLibFunc(&FDwfDigitalInInternalClockInfo, dwf, "FDwfDigitalInInternalClockInfo")
-	purego.RegisterLibFunc(&FDwfDigitalInDividerInfo, dwf, "FDwfDigitalInDividerInfo")
-	purego.RegisterLibFunc(&FDwfDigitalInDividerSet, dwf, "FDwfDigitalInDividerSet")
-	purego.RegisterLibFunc(&FDwfDigitalInDividerGet, dwf, "FDwfDigitalInDividerGet")
-	purego.RegisterLibFunc(&FDwfDigitalInSampleFormatSet, dwf, "FDwfDigitalInSampleFormatSet")
-	purego.RegisterLibFunc(&FDwfDigitalInBufferSizeInfo, dwf, "FDwfDigitalInBufferSizeInfo")
-	purego.RegisterLibFunc(&FDwfDigitalInBufferSizeSet, dwf, "FDwfDigitalInBufferSizeSet")
-	purego.RegisterLibFunc(&FDwfDigitalInBufferSizeGet, dwf, "FDwfDigitalInBufferSizeGet")
-	purego.RegisterLibFunc(&FDwfDigitalInAcquisitionModeSet, dwf, "FDwfDigitalInAcquisitionModeSet")
-	purego.RegisterLibFunc(&FDwfDigitalInTriggerSourceSet, dwf, "FDwfDigitalInTriggerSourceSet")
-	purego.RegisterLibFunc(&FDwfDigitalInTriggerSlopeSet, dwf, "FDwfDigitalInTriggerSlopeSet")
-	purego.RegisterLibFunc(&FDwfDigitalInTriggerPositionSet, dwf, "FDwfDigitalInTriggerPositionSet")
-	purego.RegisterLibFunc(&FDwfDigitalInTriggerPrefillSet, dwf, "FDwfDigitalInTriggerPrefillSet")
-	purego.RegisterLibFunc(&FDwfDigitalInTriggerAutoTimeoutSet, dwf, "FDwfDigitalInTriggerAutoTimeoutSet")
-	purego.RegisterLibFunc(&FDwfDigitalInTriggerSet, dwf, "FDwfDigitalInTriggerSet")
-}
-
 func (ad *AnalogDiscoveryDevice) CaptureLogicTransitions(req LogicCaptureRequest) (LogicCaptureResponse, error) {
 	if !ad.mu_logicAnalyzer.TryLock() {
 		return LogicCaptureResponse{}, ErrLogicCaptureBusy
@@ -220,11 +172,11 @@ func (ad *AnalogDiscoveryDevice) performLogicCaptureOnce(normalizedReq normalize
 	captureResponse.MeasurementTimeUs = measurementWindowUs(targetSamples, config.AppliedSampleRateHz)
 
 	defer func() {
-		_ = ad.dwfCall("FDwfDigitalInConfigure(stop)", FDwfDigitalInConfigure(ad.Handle, 0, 0))
-		_ = ad.dwfCall("FDwfDigitalInReset", FDwfDigitalInReset(ad.Handle))
+		_ = ad.dwfCall("FDwfDigitalInConfigure(stop)", ad.dwf.FDwfDigitalInConfigure(ad.Handle, 0, 0))
+		_ = ad.dwfCall("FDwfDigitalInReset", ad.dwf.FDwfDigitalInReset(ad.Handle))
 	}()
 
-	if err := ad.dwfCall("FDwfDigitalInConfigure(start)", FDwfDigitalInConfigure(ad.Handle, 1, 1)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInConfigure(start)", ad.dwf.FDwfDigitalInConfigure(ad.Handle, 1, 1)); err != nil {
 		return captureResponse, err
 	}
 
@@ -366,13 +318,13 @@ func (ad *AnalogDiscoveryDevice) normalizeLogicCaptureRequest(req LogicCaptureRe
 }
 
 func (ad *AnalogDiscoveryDevice) configureLogicAnalyzer(req normalizedLogicCaptureRequest) (logicAnalyzerConfig, error) {
-	if err := ad.dwfCall("FDwfDigitalInReset", FDwfDigitalInReset(ad.Handle)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInReset", ad.dwf.FDwfDigitalInReset(ad.Handle)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
-	if err := ad.dwfCall("FDwfDigitalInSampleFormatSet", FDwfDigitalInSampleFormatSet(ad.Handle, 16)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInSampleFormatSet", ad.dwf.FDwfDigitalInSampleFormatSet(ad.Handle, 16)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
-	if err := ad.dwfCall("FDwfDigitalInAcquisitionModeSet", FDwfDigitalInAcquisitionModeSet(ad.Handle, dwfAcqModeSingle)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInAcquisitionModeSet", ad.dwf.FDwfDigitalInAcquisitionModeSet(ad.Handle, dwfAcqModeSingle)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
 
@@ -381,12 +333,12 @@ func (ad *AnalogDiscoveryDevice) configureLogicAnalyzer(req normalizedLogicCaptu
 	if divider < 1 {
 		divider = 1
 	}
-	if err := ad.dwfCall("FDwfDigitalInDividerSet", FDwfDigitalInDividerSet(ad.Handle, divider)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInDividerSet", ad.dwf.FDwfDigitalInDividerSet(ad.Handle, divider)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
 
 	var appliedDivider int
-	if err := ad.dwfCall("FDwfDigitalInDividerGet", FDwfDigitalInDividerGet(ad.Handle, &appliedDivider)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInDividerGet", ad.dwf.FDwfDigitalInDividerGet(ad.Handle, &appliedDivider)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
 	if appliedDivider <= 0 {
@@ -398,11 +350,11 @@ func (ad *AnalogDiscoveryDevice) configureLogicAnalyzer(req normalizedLogicCaptu
 	}
 
 	bufferSize := req.bufferMax
-	if err := ad.dwfCall("FDwfDigitalInBufferSizeSet", FDwfDigitalInBufferSizeSet(ad.Handle, bufferSize)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInBufferSizeSet", ad.dwf.FDwfDigitalInBufferSizeSet(ad.Handle, bufferSize)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
 	actualBufferSize := bufferSize
-	if err := ad.dwfCall("FDwfDigitalInBufferSizeGet", FDwfDigitalInBufferSizeGet(ad.Handle, &actualBufferSize)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInBufferSizeGet", ad.dwf.FDwfDigitalInBufferSizeGet(ad.Handle, &actualBufferSize)); err != nil {
 		log.Printf("logic analyzer buffer size get failed; using configured value=%d err=%v", bufferSize, err)
 		actualBufferSize = bufferSize
 	}
@@ -417,7 +369,7 @@ func (ad *AnalogDiscoveryDevice) configureLogicAnalyzer(req normalizedLogicCaptu
 	}
 
 	if req.Trigger.Type == "immediate" {
-		if err := ad.dwfCall("FDwfDigitalInTriggerSourceSet", FDwfDigitalInTriggerSourceSet(ad.Handle, dwfTrigSrcNone)); err != nil {
+		if err := ad.dwfCall("FDwfDigitalInTriggerSourceSet", ad.dwf.FDwfDigitalInTriggerSourceSet(ad.Handle, dwfTrigSrcNone)); err != nil {
 			return logicAnalyzerConfig{}, err
 		}
 		return config, nil
@@ -425,19 +377,19 @@ func (ad *AnalogDiscoveryDevice) configureLogicAnalyzer(req normalizedLogicCaptu
 
 	edgeRise, edgeFall, _ := digitalTriggerEdgeConfig(req.Trigger.Channel, req.Trigger.Edge)
 
-	if err := ad.dwfCall("FDwfDigitalInTriggerSourceSet", FDwfDigitalInTriggerSourceSet(ad.Handle, dwfTrigSrcDetectorDigitalIn)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInTriggerSourceSet", ad.dwf.FDwfDigitalInTriggerSourceSet(ad.Handle, dwfTrigSrcDetectorDigitalIn)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
-	if err := ad.dwfCall("FDwfDigitalInTriggerPositionSet", FDwfDigitalInTriggerPositionSet(ad.Handle, uint32(actualBufferSize))); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInTriggerPositionSet", ad.dwf.FDwfDigitalInTriggerPositionSet(ad.Handle, uint32(actualBufferSize))); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
-	if err := ad.dwfCall("FDwfDigitalInTriggerPrefillSet", FDwfDigitalInTriggerPrefillSet(ad.Handle, 0)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInTriggerPrefillSet", ad.dwf.FDwfDigitalInTriggerPrefillSet(ad.Handle, 0)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
-	if err := ad.dwfCall("FDwfDigitalInTriggerAutoTimeoutSet", FDwfDigitalInTriggerAutoTimeoutSet(ad.Handle, 0)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInTriggerAutoTimeoutSet", ad.dwf.FDwfDigitalInTriggerAutoTimeoutSet(ad.Handle, 0)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
-	if err := ad.dwfCall("FDwfDigitalInTriggerSet", FDwfDigitalInTriggerSet(ad.Handle, 0, 0, edgeRise, edgeFall)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInTriggerSet", ad.dwf.FDwfDigitalInTriggerSet(ad.Handle, 0, 0, edgeRise, edgeFall)); err != nil {
 		return logicAnalyzerConfig{}, err
 	}
 
@@ -481,7 +433,7 @@ func (ad *AnalogDiscoveryDevice) collectLogicSamples(req normalizedLogicCaptureR
 	idleSleep := adaptiveIdleMin
 	for {
 		var status byte
-		if err := ad.dwfCall("FDwfDigitalInStatus", FDwfDigitalInStatus(ad.Handle, 0, &status)); err != nil {
+		if err := ad.dwfCall("FDwfDigitalInStatus", ad.dwf.FDwfDigitalInStatus(ad.Handle, 0, &status)); err != nil {
 			return nil, false, nil, err
 		}
 		if status == dwfStateDone {
@@ -511,14 +463,14 @@ func (ad *AnalogDiscoveryDevice) collectLogicSamples(req normalizedLogicCaptureR
 	}
 
 	var readStatus byte
-	if err := ad.dwfCall("FDwfDigitalInStatus(readData)", FDwfDigitalInStatus(ad.Handle, 1, &readStatus)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInStatus(readData)", ad.dwf.FDwfDigitalInStatus(ad.Handle, 1, &readStatus)); err != nil {
 		return nil, false, nil, err
 	}
 
 	dataAvailable := 0
 	totalLost := 0
 	totalCorrupt := 0
-	if err := ad.dwfCall("FDwfDigitalInStatusRecord", FDwfDigitalInStatusRecord(ad.Handle, &dataAvailable, &totalLost, &totalCorrupt)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInStatusRecord", ad.dwf.FDwfDigitalInStatusRecord(ad.Handle, &dataAvailable, &totalLost, &totalCorrupt)); err != nil {
 		log.Printf("logic analyzer status record read failed in single mode: err=%v", err)
 		dataAvailable = 0
 		totalLost = 0
@@ -528,7 +480,7 @@ func (ad *AnalogDiscoveryDevice) collectLogicSamples(req normalizedLogicCaptureR
 	samples := make([]uint16, targetSamples)
 	bytesToRead := targetSamples * logicAnalyzerSampleBytes
 	destBytes := unsafe.Slice((*byte)(unsafe.Pointer(&samples[0])), bytesToRead)
-	if err := ad.dwfCall("FDwfDigitalInStatusData", FDwfDigitalInStatusData(ad.Handle, &destBytes[0], bytesToRead)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInStatusData", ad.dwf.FDwfDigitalInStatusData(ad.Handle, &destBytes[0], bytesToRead)); err != nil {
 		log.Printf("logic analyzer read error: op=FDwfDigitalInStatusData toReadSamples=%d toReadBytes=%d configuredRate=%dHz err=%v",
 			targetSamples, bytesToRead, config.AppliedSampleRateHz, err)
 		return nil, false, nil, err
@@ -667,7 +619,7 @@ func bitValue(word uint16, channel int) int {
 
 func (ad *AnalogDiscoveryDevice) getDigitalInClockAndDividerInfo() (float64, error) {
 	var internalClock float64
-	if err := ad.dwfCall("FDwfDigitalInInternalClockInfo", FDwfDigitalInInternalClockInfo(ad.Handle, &internalClock)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInInternalClockInfo", ad.dwf.FDwfDigitalInInternalClockInfo(ad.Handle, &internalClock)); err != nil {
 		return 0, err
 	}
 	if internalClock <= 0 {
@@ -679,7 +631,7 @@ func (ad *AnalogDiscoveryDevice) getDigitalInClockAndDividerInfo() (float64, err
 
 func (ad *AnalogDiscoveryDevice) getDigitalInBufferSizeMax() (int, error) {
 	var bufferMax int
-	if err := ad.dwfCall("FDwfDigitalInBufferSizeInfo", FDwfDigitalInBufferSizeInfo(ad.Handle, &bufferMax)); err != nil {
+	if err := ad.dwfCall("FDwfDigitalInBufferSizeInfo", ad.dwf.FDwfDigitalInBufferSizeInfo(ad.Handle, &bufferMax)); err != nil {
 		return 0, err
 	}
 	if bufferMax <= 0 {
@@ -692,16 +644,16 @@ func (ad *AnalogDiscoveryDevice) dwfCall(op string, result int32) error {
 	if result != 0 {
 		return nil
 	}
-	message := getLastDwfErrorMessage()
+	message := getLastDwfErrorMessage(ad.dwf)
 	if message == "" {
 		message = "unknown DWF error"
 	}
 	return DeviceRuntimeError{Message: fmt.Sprintf("%s failed: %s", op, message)}
 }
 
-func getLastDwfErrorMessage() string {
+func getLastDwfErrorMessage(dwf Backend) string {
 	buf := make([]byte, 512)
-	FDwfGetLastErrorMsg(&buf[0])
+	dwf.FDwfGetLastErrorMsg(&buf[0])
 	message := string(buf)
 	if idx := strings.IndexByte(message, 0); idx >= 0 {
 		message = message[:idx]
@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Only this much of a failed response is kept to find its error message
+const maxCapturedResponse = 4 << 10
+
+// Middleware records every request that changes something in the log of the
+// session it ran in. who returns that session and the role of the client.
+// Reads are not recorded.
+func Middleware(l *Log, who func(c *gin.Context) (session, role string)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		// The session may end while the request runs, so it is taken up front
+		session, role := who(c)
+
+		var params json.RawMessage
+		if c.Request.Body != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+			data, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(data))
+			params = normalizeJSON(data)
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if form := c.Request.MultipartForm; form != nil {
+			params = multipartParams(form.Value, form.File)
+		}
+
+		entry := Entry{
+			Time:     time.Now(),
+			Role:     role,
+			Endpoint: c.Request.Method + " " + c.Request.URL.Path,
+			Params:   params,
+			Status:   writer.Status(),
+			Result:   ResultOK,
+		}
+		if entry.Status >= http.StatusBadRequest {
+			entry.Result = ResultError
+			entry.Error = errorMessage(writer.body.Bytes(), entry.Status)
+		}
+		if err := l.Append(session, entry); err != nil {
+			log.Printf("Error writing audit log: %v", err)
+		}
+	}
+}
+
+// capturingWriter keeps the start of the response body
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *capturingWriter) capture(data []byte) {
+	if room := maxCapturedResponse - w.body.Len(); room > 0 {
+		w.body.Write(data[:min(len(data), room)])
+	}
+}
+
+// normalizeJSON rewrites a JSON body compactly with sorted keys, so equal
+// parameters always look the same in the log. Anything else is kept as a string.
+func normalizeJSON(data []byte) json.RawMessage {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err == nil {
+		if normalized, err := json.Marshal(value); err == nil {
+			return normalized
+		}
+	}
+	quoted, _ := json.Marshal(string(data))
+	return quoted
+}
+
+// multipartParams describes an upload by its fields and the names and sizes of its files
+func multipartParams(values map[string][]string, files map[string][]*multipart.FileHeader) json.RawMessage {
+	params := map[string]any{}
+	for name, value := range values {
+		params[name] = value
+	}
+	for name, headers := range files {
+		described := make([]gin.H, 0, len(headers))
+		for _, header := range headers {
+			described = append(described, gin.H{"filename": header.Filename, "size": header.Size})
+		}
+		params[name] = described
+	}
+	data, _ := json.Marshal(params)
+	return data
+}
+
+// errorMessage pulls the "error" field out of a failed JSON response
+func errorMessage(body []byte, status int) string {
+	var response struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &response) == nil && response.Error != "" {
+		return response.Error
+	}
+	if text := strings.TrimSpace(string(body)); text != "" && len(body) < maxCapturedResponse {
+		return text
+	}
+	return http.StatusText(status)
+}
+
+// handler listing the sessions that have an audit log
+func HandleListLogs(l *Log) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		logs, err := l.List()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, logs)
+	}
+}
+
+// handler downloading the audit log of one session as JSON lines
+func HandleDownloadLog(l *Log) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		session := c.Param("session")
+		path, err := l.Path(session)
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="`+session+`.jsonl"`)
+		c.File(path)
+	}
+}
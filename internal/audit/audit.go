@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is one action of a session client
+type Entry struct {
+	Time     time.Time       `json:"time"`
+	Role     string          `json:"role,omitempty"`
+	Endpoint string          `json:"endpoint"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Status   int             `json:"status,omitempty"`
+	Result   string          `json:"result"`
+	Error    string          `json:"error,omitempty"`
+}
+
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// SessionLog describes the log of one session
+type SessionLog struct {
+	Session    string    `json:"session"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+var ErrNotFound = errors.New("no audit log for this session")
+
+// Session IDs become file names, so anything that could leave the directory is refused
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Log appends entries to one JSONL file per session in a directory. Files are
+// only ever appended to.
+type Log struct {
+	mu sync.Mutex
+	// dir is read on every write so a config reload can move it. Empty turns logging off.
+	dir func() string
+}
+
+func NewLog(dir func() string) *Log {
+	return &Log{dir: dir}
+}
+
+func (l *Log) Append(session string, entry Entry) error {
+	dir := l.dir()
+	if dir == "" || session == "" {
+		return nil
+	}
+	if !sessionIDPattern.MatchString(session) {
+		return fmt.Errorf("invalid session id %q", session)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, session+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// List returns the session logs, newest first
+func (l *Log) List() ([]SessionLog, error) {
+	dir := l.dir()
+	if dir == "" {
+		return []SessionLog{}, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []SessionLog{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []SessionLog{}
+	for _, entry := range entries {
+		session, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, SessionLog{Session: session, Size: info.Size(), ModifiedAt: info.ModTime()})
+	}
+	slices.SortFunc(logs, func(a, b SessionLog) int {
+		return strings.Compare(b.Session, a.Session)
+	})
+	return logs, nil
+}
+
+// Path returns the file holding the log of session
+func (l *Log) Path(session string) (string, error) {
+	dir := l.dir()
+	if dir == "" || !sessionIDPattern.MatchString(session) {
+		return "", ErrNotFound
+	}
+	path := filepath.Join(dir, session+".jsonl")
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrNotFound
+	}
+	return path, nil
+}
@@ -0,0 +1,184 @@
+package camera
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FileSource loops over prerecorded frames: every JPEG in a directory in name
+// order, or every frame of a Motion-JPEG file
+type FileSource struct {
+	path   string
+	frames [][]byte
+	next   int
+	mu     sync.Mutex
+	pacer  *pacer
+}
+
+func NewFileSource(path string, fps int) (*FileSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frame source: %v", err)
+	}
+
+	var frames [][]byte
+	if info.IsDir() {
+		frames, err = loadJPEGDirectory(path)
+	} else {
+		frames, err = loadMJPEGFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no JPEG frames found in %s", path)
+	}
+
+	return &FileSource{
+		path:   path,
+		frames: frames,
+		pacer:  newPacer(fps),
+	}, nil
+}
+
+func loadJPEGDirectory(dir string) ([][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frame directory: %v", err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".jpg" || ext == ".jpeg") {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+
+	frames := make([][]byte, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read frame %s: %v", name, err)
+		}
+		frames = append(frames, data)
+	}
+	return frames, nil
+}
+
+// loadMJPEGFile splits a stream of concatenated JPEG images. A single .jpg is a one-frame stream.
+func loadMJPEGFile(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	frames := [][]byte{}
+	for {
+		start := bytes.Index(data, []byte{0xFF, 0xD8})
+		if start < 0 {
+			break
+		}
+		end, err := jpegEnd(data[start:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: frame %d: %v", path, len(frames), err)
+		}
+		frames = append(frames, data[start:start+end])
+		data = data[start+end:]
+	}
+	return frames, nil
+}
+
+// jpegEnd returns the length of the JPEG image at the start of data. It walks the
+// marker segments up to the scan data, so EOI markers inside embedded thumbnails are skipped.
+func jpegEnd(data []byte) (int, error) {
+	i := 2 // past SOI
+	for {
+		if i+2 > len(data) || data[i] != 0xFF {
+			return 0, fmt.Errorf("malformed JPEG marker at offset %d", i)
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			i++ // fill byte
+			continue
+		}
+		if marker == 0xD9 {
+			return i + 2, nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2 // markers without a length field
+			continue
+		}
+		if i+4 > len(data) {
+			return 0, fmt.Errorf("truncated JPEG segment at offset %d", i)
+		}
+		segmentLength := int(data[i+2])<<8 | int(data[i+3])
+		i += 2 + segmentLength
+		if marker != 0xDA {
+			continue
+		}
+
+		// Entropy-coded data: stuffed FF00 and restart markers belong to the scan
+		for ; i+1 < len(data); i++ {
+			if data[i] != 0xFF {
+				continue
+			}
+			next := data[i+1]
+			if next == 0x00 || (next >= 0xD0 && next <= 0xD7) {
+				i++
+				continue
+			}
+			break
+		}
+		if i+1 >= len(data) {
+			return 0, fmt.Errorf("missing end of image")
+		}
+	}
+}
+
+func (s *FileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *FileSource) Info() SourceInfo {
+	info := SourceInfo{Source: s.path, Format: "Motion-JPEG", FPS: s.pacer.fps}
+	if config, err := jpeg.DecodeConfig(bytes.NewReader(s.frames[0])); err == nil {
+		info.Width = config.Width
+		info.Height = config.Height
+	}
+	return info
+}
+
+func (s *FileSource) Start() error {
+	s.pacer.start()
+	return nil
+}
+
+func (s *FileSource) Stop() error {
+	s.pacer.stop()
+	return nil
+}
+
+func (s *FileSource) Close() error {
+	return s.Stop()
+}
+
+func (s *FileSource) NextFrame() ([]byte, error) {
+	if !s.pacer.wait() {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frame := s.frames[s.next]
+	s.next = (s.next + 1) % len(s.frames)
+	return frame, nil
+}
@@ -0,0 +1,62 @@
+package camera
+
+import (
+	"sync"
+	"time"
+)
+
+// pacer releases frames at a fixed rate for sources that are not clocked by hardware
+type pacer struct {
+	fps      int
+	interval time.Duration
+	mu       sync.Mutex
+	ticker   *time.Ticker
+	done     chan struct{}
+}
+
+func newPacer(fps int) *pacer {
+	if fps <= 0 {
+		fps = 1
+	}
+	return &pacer{fps: fps, interval: time.Second / time.Duration(fps)}
+}
+
+func (p *pacer) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ticker != nil {
+		return
+	}
+	p.ticker = time.NewTicker(p.interval)
+	p.done = make(chan struct{})
+}
+
+func (p *pacer) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ticker == nil {
+		return
+	}
+	p.ticker.Stop()
+	close(p.done)
+	p.ticker = nil
+}
+
+// wait blocks until the next frame is due. It returns false once the pacer is stopped.
+func (p *pacer) wait() bool {
+	p.mu.Lock()
+	ticker, done := p.ticker, p.done
+	p.mu.Unlock()
+
+	if ticker == nil {
+		return false
+	}
+	select {
+	case <-ticker.C:
+		return true
+	case <-done:
+		return false
+	}
+}
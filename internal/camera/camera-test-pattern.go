@@ -0,0 +1,98 @@
+package camera
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+var testPatternBars = []color.RGBA{
+	{192, 192, 192, 255},
+	{192, 192, 0, 255},
+	{0, 192, 192, 255},
+	{0, 192, 0, 255},
+	{192, 0, 192, 255},
+	{192, 0, 0, 255},
+	{0, 0, 192, 255},
+}
+
+// TestPatternSource renders color bars with a sweeping marker, so a stalled
+// stream is easy to tell apart from a live one
+type TestPatternSource struct {
+	width  int
+	height int
+	frame  int
+	pacer  *pacer
+}
+
+func NewTestPatternSource(width, height, fps int) *TestPatternSource {
+	return &TestPatternSource{
+		width:  width,
+		height: height,
+		pacer:  newPacer(fps),
+	}
+}
+
+func (s *TestPatternSource) Name() string {
+	return "test-pattern"
+}
+
+func (s *TestPatternSource) Info() SourceInfo {
+	return SourceInfo{
+		Source: "test-pattern",
+		Format: "Motion-JPEG",
+		Width:  s.width,
+		Height: s.height,
+		FPS:    s.pacer.fps,
+	}
+}
+
+func (s *TestPatternSource) Start() error {
+	s.pacer.start()
+	return nil
+}
+
+func (s *TestPatternSource) Stop() error {
+	s.pacer.stop()
+	return nil
+}
+
+func (s *TestPatternSource) Close() error {
+	return s.Stop()
+}
+
+func (s *TestPatternSource) NextFrame() ([]byte, error) {
+	if !s.pacer.wait() {
+		return nil, nil
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	barsHeight := s.height * 3 / 4
+	for x := 0; x < s.width; x++ {
+		bar := testPatternBars[x*len(testPatternBars)/s.width]
+		for y := 0; y < barsHeight; y++ {
+			img.SetRGBA(x, y, bar)
+		}
+	}
+
+	// Marker moves across the bottom strip, one step per frame
+	markerWidth := max(s.width/32, 1)
+	markerX := (s.frame * markerWidth) % s.width
+	for y := barsHeight; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			c := color.RGBA{16, 16, 16, 255}
+			if x >= markerX && x < markerX+markerWidth {
+				c = color.RGBA{235, 235, 235, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	s.frame++
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
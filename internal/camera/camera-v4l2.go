@@ -0,0 +1,120 @@
+package camera
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/blackjack/webcam"
+	"golang.org/x/sys/unix"
+)
+
+const desiredBufferCount uint32 = 4
+
+// V4L2Source captures Motion-JPEG frames from a V4L2 webcam
+type V4L2Source struct {
+	cam        *webcam.Webcam
+	devicePath string
+	width      int
+	height     int
+	formats    []string
+}
+
+func NewV4L2Source(devicePath string, width, height int) (*V4L2Source, error) {
+	cam, err := webcam.Open(devicePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open webcam: %v", err)
+	}
+
+	// webcam defaults to a very high buffer count (256). Keep this small for low-latency live preview.
+	if err := cam.SetBufferCount(desiredBufferCount); err != nil {
+		cam.Close()
+		return nil, fmt.Errorf("failed to set buffer count: %v", err)
+	}
+
+	formats := cam.GetSupportedFormats()
+	var selectedFormat webcam.PixelFormat
+	var formatNames []string
+	for f, s := range formats {
+		fmt.Printf("Supported format: %s\n", s)
+		formatNames = append(formatNames, s)
+		if s == "Motion-JPEG" {
+			selectedFormat = f
+		}
+	}
+	slices.Sort(formatNames)
+
+	if selectedFormat == 0 {
+		cam.Close()
+		return nil, fmt.Errorf("Motion-JPEG format not supported")
+	}
+
+	_, appliedWidth, appliedHeight, err := cam.SetImageFormat(selectedFormat, uint32(width), uint32(height))
+	if err != nil {
+		cam.Close()
+		return nil, fmt.Errorf("failed to set image format: %v", err)
+	}
+
+	return &V4L2Source{
+		cam:        cam,
+		devicePath: devicePath,
+		width:      int(appliedWidth),
+		height:     int(appliedHeight),
+		formats:    formatNames,
+	}, nil
+}
+
+func (s *V4L2Source) Name() string {
+	return "v4l2:" + s.devicePath
+}
+
+func (s *V4L2Source) Info() SourceInfo {
+	return SourceInfo{
+		Source:           s.devicePath,
+		Format:           "Motion-JPEG",
+		Width:            s.width,
+		Height:           s.height,
+		SupportedFormats: s.formats,
+	}
+}
+
+func (s *V4L2Source) Start() error {
+	return s.cam.StartStreaming()
+}
+
+func (s *V4L2Source) Stop() error {
+	return s.cam.StopStreaming()
+}
+
+func (s *V4L2Source) Close() error {
+	return s.cam.Close()
+}
+
+func (s *V4L2Source) NextFrame() ([]byte, error) {
+	err := s.cam.WaitForFrame(uint32(5))
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for frame: %v", err)
+	}
+
+	// Drain all available frames and keep only the most recent one.
+	var latestFrame []byte
+	for {
+		frameData, err := s.cam.ReadFrame()
+		if err != nil {
+			// Non-blocking V4L2 read returns EAGAIN when no more queued frames are available.
+			if errors.Is(err, unix.EAGAIN) {
+				break
+			}
+			log.Printf("Error reading frame: %v", err)
+			break
+		}
+		if len(frameData) == 0 {
+			break
+		}
+
+		latestFrame = append(latestFrame[:0], frameData...)
+	}
+
+	return latestFrame, nil
+}
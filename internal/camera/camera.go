@@ -1,20 +1,38 @@
 package camera
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"sync"
 	"sync/atomic"
 
-	"github.com/blackjack/webcam"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/sys/unix"
 )
 
+// FrameSource produces the JPEG frames served on the stream
+type FrameSource interface {
+	Name() string
+	Start() error
+	Stop() error
+	// NextFrame blocks until the next frame is available. An empty frame without an error means there was nothing new.
+	NextFrame() ([]byte, error)
+	Close() error
+	Info() SourceInfo
+}
+
+// SourceInfo describes the frames a source produces
+type SourceInfo struct {
+	Source           string   `json:"source"`
+	Format           string   `json:"format"`
+	Width            int      `json:"width"`
+	Height           int      `json:"height"`
+	FPS              int      `json:"fps,omitempty"`
+	SupportedFormats []string `json:"supportedFormats,omitempty"`
+}
+
 type WebcamServer struct {
-	cam           *webcam.Webcam
+	source        FrameSource
 	frame         []byte
 	mutex         sync.Mutex
 	clients       map[chan []byte]bool
@@ -23,42 +41,15 @@ type WebcamServer struct {
 	streamingLock sync.Mutex
 }
 
-const desiredBufferCount uint32 = 4
-
-func NewWebcamServer(devicePath string) (*WebcamServer, error) {
-	cam, err := webcam.Open(devicePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open webcam: %v", err)
-	}
-
-	// webcam defaults to a very high buffer count (256). Keep this small for low-latency live preview.
-	if err := cam.SetBufferCount(desiredBufferCount); err != nil {
-		return nil, fmt.Errorf("failed to set buffer count: %v", err)
-	}
-
-	formats := cam.GetSupportedFormats()
-	var selectedFormat webcam.PixelFormat
-	for f, s := range formats {
-		fmt.Printf("Supported format: %s\n", s)
-		if s == "Motion-JPEG" {
-			selectedFormat = f
-			break
-		}
-	}
-
-	if selectedFormat == 0 {
-		return nil, fmt.Errorf("Motion-JPEG format not supported")
-	}
-
-	_, _, _, err = cam.SetImageFormat(selectedFormat, 1920, 1080)
-	if err != nil {
-		return nil, fmt.Errorf("failed to set image format: %v", err)
-	}
-
+func NewWebcamServer(source FrameSource) *WebcamServer {
 	return &WebcamServer{
-		cam:     cam,
+		source:  source,
 		clients: make(map[chan []byte]bool),
-	}, nil
+	}
+}
+
+func (ws *WebcamServer) Info() SourceInfo {
+	return ws.source.Info()
 }
 
 func (ws *WebcamServer) StartStreaming() error {
@@ -69,7 +60,7 @@ func (ws *WebcamServer) StartStreaming() error {
 		return nil // Already streaming
 	}
 
-	err := ws.cam.StartStreaming()
+	err := ws.source.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start streaming: %v", err)
 	}
@@ -87,42 +78,23 @@ func (ws *WebcamServer) stopStreaming() {
 		return // Not streaming
 	}
 
-	ws.cam.StopStreaming()
+	ws.source.Stop()
 	ws.isStreaming.Store(false)
 }
 
 func (ws *WebcamServer) Close() {
 	ws.stopStreaming()
-	ws.cam.Close()
+	ws.source.Close()
 }
 
 func (ws *WebcamServer) captureFrames() {
 	for ws.isStreaming.Load() {
-		err := ws.cam.WaitForFrame(uint32(5))
+		latestFrame, err := ws.source.NextFrame()
 		if err != nil {
-			log.Printf("Error waiting for frame: %v", err)
+			log.Printf("Error capturing frame from %s: %v", ws.source.Name(), err)
 			continue
 		}
 
-		// Drain all available frames and keep only the most recent one.
-		var latestFrame []byte
-		for {
-			frameData, err := ws.cam.ReadFrame()
-			if err != nil {
-				// Non-blocking V4L2 read returns EAGAIN when no more queued frames are available.
-				if errors.Is(err, unix.EAGAIN) {
-					break
-				}
-				log.Printf("Error reading frame: %v", err)
-				break
-			}
-			if len(frameData) == 0 {
-				break
-			}
-
-			latestFrame = append(latestFrame[:0], frameData...)
-		}
-
 		if len(latestFrame) == 0 {
 			continue
 		}
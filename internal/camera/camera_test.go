@@ -0,0 +1,253 @@
+package camera
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// countingSource is a test pattern that counts starts and stops and numbers
+// the frames it hands out
+type countingSource struct {
+	*TestPatternSource
+	starts atomic.Int32
+	stops  atomic.Int32
+
+	mu     sync.Mutex
+	frames map[string]int
+}
+
+func newCountingSource() *countingSource {
+	return &countingSource{
+		TestPatternSource: NewTestPatternSource(64, 48, 50),
+		frames:            map[string]int{},
+	}
+}
+
+func (s *countingSource) Start() error {
+	s.starts.Add(1)
+	return s.TestPatternSource.Start()
+}
+
+func (s *countingSource) Stop() error {
+	s.stops.Add(1)
+	return s.TestPatternSource.Stop()
+}
+
+func (s *countingSource) NextFrame() ([]byte, error) {
+	frame, err := s.TestPatternSource.NextFrame()
+	if len(frame) > 0 {
+		s.mu.Lock()
+		s.frames[string(frame)] = len(s.frames)
+		s.mu.Unlock()
+	}
+	return frame, err
+}
+
+// index returns the position of a frame in the produced sequence
+func (s *countingSource) index(frame []byte) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.frames[string(frame)]
+	return i, ok
+}
+
+func (s *countingSource) produced() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.frames)
+}
+
+func newStreamServer(t *testing.T) (*WebcamServer, *countingSource, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	source := newCountingSource()
+	ws := NewWebcamServer(source)
+	router := gin.New()
+	router.GET("/api/stream", ws.ServeHTTP)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		server.CloseClientConnections()
+		server.Close()
+		ws.Close()
+	})
+	return ws, source, server.URL + "/api/stream"
+}
+
+type streamClient struct {
+	body   io.ReadCloser
+	parts  *multipart.Reader
+	cancel context.CancelFunc
+}
+
+func openStream(t *testing.T, url string) *streamClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "multipart/x-mixed-replace; boundary=frame" {
+		t.Fatalf("Content-Type = %q", contentType)
+	}
+
+	client := &streamClient{
+		body:   response.Body,
+		parts:  multipart.NewReader(response.Body, "frame"),
+		cancel: cancel,
+	}
+	t.Cleanup(client.close)
+	return client
+}
+
+func (c *streamClient) close() {
+	c.cancel()
+	c.body.Close()
+}
+
+// next reads one JPEG part off the stream
+func (c *streamClient) next(t *testing.T) []byte {
+	t.Helper()
+	timer := time.AfterFunc(5*time.Second, c.close)
+	defer timer.Stop()
+
+	part, err := c.parts.NextPart()
+	if err != nil {
+		t.Fatalf("reading the next part: %v", err)
+	}
+	if contentType := part.Header.Get("Content-Type"); contentType != "image/jpeg" {
+		t.Fatalf("part Content-Type = %q", contentType)
+	}
+	frame, err := io.ReadAll(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatalf("part is not a JPEG: %v", err)
+	}
+	if config.Width != 64 || config.Height != 48 {
+		t.Fatalf("frame is %dx%d, want 64x48", config.Width, config.Height)
+	}
+	return frame
+}
+
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting until %s", what)
+}
+
+// Every client gets the frames of the one running source
+func TestStreamFansOutToClients(t *testing.T) {
+	_, source, url := newStreamServer(t)
+
+	clients := []*streamClient{openStream(t, url), openStream(t, url), openStream(t, url)}
+	last := make([]int, len(clients))
+	for round := 0; round < 5; round++ {
+		for i, client := range clients {
+			index, ok := source.index(client.next(t))
+			if !ok {
+				t.Fatalf("client %d got a frame the source never produced", i)
+			}
+			if round > 0 && index <= last[i] {
+				t.Errorf("client %d got frame %d after frame %d", i, index, last[i])
+			}
+			last[i] = index
+		}
+	}
+
+	if starts := source.starts.Load(); starts != 1 {
+		t.Errorf("the source was started %d times for concurrent clients", starts)
+	}
+}
+
+// A client that doesn't keep up holds only the newest frame, and doesn't hold
+// back the others
+func TestSlowClientDropsFrames(t *testing.T) {
+	ws, source, url := newStreamServer(t)
+
+	slow := make(chan []byte, 1)
+	ws.clientsMutex.Lock()
+	ws.clients[slow] = true
+	ws.clientsMutex.Unlock()
+
+	fast := openStream(t, url)
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		fast.next(t)
+	}
+	// 20 frames at 50 fps, with plenty of slack for a loaded machine
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the fast client needed %s for 20 frames", elapsed)
+	}
+
+	produced := source.produced()
+	select {
+	case frame := <-slow:
+		index, ok := source.index(frame)
+		if !ok {
+			t.Fatal("the slow client holds a frame the source never produced")
+		}
+		if index < produced-3 {
+			t.Errorf("the slow client holds frame %d of %d, want one of the newest", index, produced)
+		}
+	default:
+		t.Fatal("the slow client got no frame at all")
+	}
+}
+
+// The source runs only while somebody watches
+func TestStreamStopsWithLastClient(t *testing.T) {
+	ws, source, url := newStreamServer(t)
+
+	if ws.isStreaming.Load() || source.starts.Load() != 0 {
+		t.Fatal("the source started without a client")
+	}
+
+	first := openStream(t, url)
+	first.next(t)
+	second := openStream(t, url)
+	second.next(t)
+
+	first.close()
+	// The remaining client keeps getting frames from the same run
+	for i := 0; i < 3; i++ {
+		second.next(t)
+	}
+	if stops := source.stops.Load(); stops != 0 {
+		t.Fatalf("the source was stopped %d times while a client was left", stops)
+	}
+
+	second.close()
+	eventually(t, "the source is stopped", func() bool {
+		return source.stops.Load() == 1 && !ws.isStreaming.Load()
+	})
+
+	third := openStream(t, url)
+	third.next(t)
+	if starts := source.starts.Load(); starts != 2 {
+		t.Errorf("the source was started %d times, want a restart for the new client", starts)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+)
+
+// How a field may change while the backend is running
+const (
+	// ReloadHot fields are applied right away
+	ReloadHot = "hot"
+	// ReloadIdle fields reopen hardware, so they are only applied without an active session
+	ReloadIdle = "idle"
+	// ReloadRestart fields only take effect after a restart
+	ReloadRestart = "restart"
+)
+
+// Change is a field that differs between two configurations
+type Change struct {
+	Field  string `json:"field"`
+	Reload string `json:"reload"`
+}
+
+// Diff lists the fields that differ between old and new, named by their YAML path
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	diffStruct(reflect.ValueOf(*old), reflect.ValueOf(*new), "", &changes)
+	return changes
+}
+
+func diffStruct(old, new reflect.Value, prefix string, changes *[]Change) {
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		name := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(old.Field(i), new.Field(i), name+".", changes)
+			continue
+		}
+		if reflect.DeepEqual(old.Field(i).Interface(), new.Field(i).Interface()) {
+			continue
+		}
+		reload := field.Tag.Get("reload")
+		if reload == "" {
+			reload = ReloadRestart
+		}
+		*changes = append(*changes, Change{Field: name, Reload: reload})
+	}
+}
+
+// Live holds the configuration in effect. Readers should call Get whenever they
+// need a setting instead of keeping the result, so reloads reach them.
+type Live struct {
+	path    string
+	current atomic.Pointer[Config]
+}
+
+func NewLive(path string, cfg *Config) *Live {
+	live := &Live{path: path}
+	live.current.Store(cfg)
+	return live
+}
+
+func (l *Live) Get() *Config {
+	return l.current.Load()
+}
+
+func (l *Live) Set(cfg *Config) {
+	l.current.Store(cfg)
+}
+
+// Path is the file the configuration is reloaded from
+func (l *Live) Path() string {
+	return l.path
+}
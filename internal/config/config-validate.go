@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// usbID is a USB vendor or product ID as the system reports it, e.g. 0483
+var usbID = regexp.MustCompile(`^[0-9A-Fa-f]{4}$`)
+
+// Validate checks the configuration and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(field, value string, allowed ...string) {
+		check(slices.Contains(allowed, value), "%s: %q is not one of %s", field, value, strings.Join(allowed, ", "))
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port: %d is not a valid port", c.Server.Port)
+	check(c.Server.UploadDir != "", "server.upload_dir: must be set")
+	check(c.Server.MaxUploadSize > 0, "server.max_upload_size: must be positive")
+	check(c.Auth.MasterServerAPISecret != "", "auth.master_server_api_secret: must be set (or MASTER_SERVER_API_SECRET)")
+	check(c.Auth.SessionTokenKey == "" || c.Station.ID != "", "station.id: must be set when auth.session_token_key is")
+	check(c.Session.ReconnectWindow > 0, "session.reconnect_window: must be positive")
+	check(c.Session.IdleTimeout >= 0, "session.idle_timeout: must not be negative")
+	check(c.Session.IdleWarning >= 0, "session.idle_warning: must not be negative")
+	check(c.Session.CountdownInterval >= 0, "session.countdown_interval: must not be negative")
+	for _, warning := range c.Session.ExpiryWarnings {
+		check(warning > 0, "session.expiry_warnings: %s is not positive", warning)
+	}
+	check(!hasDuplicates(c.Session.ExpiryWarnings), "session.expiry_warnings: warnings must be unique")
+	check(c.MasterServer.URL == "" || strings.HasPrefix(c.MasterServer.URL, "http://") || strings.HasPrefix(c.MasterServer.URL, "https://"),
+		"master_server.url: %q is not an http(s) URL", c.MasterServer.URL)
+	check(c.MasterServer.RetryMax > 0, "master_server.retry_max: must be positive")
+	for _, step := range c.StationReset.Steps {
+		oneOf("station_reset.steps", step, "wavegen", "outputs", "potentiometer", "multiplexer", "uart", "firmware")
+	}
+	check(!hasDuplicates(c.StationReset.Steps), "station_reset.steps: steps must be unique")
+	check(c.Peripherals.RetryInterval > 0, "peripherals.retry_interval: must be positive")
+
+	oneOf("gpio.backend", c.GPIO.Backend, "cdev", "fake")
+	check(c.GPIO.Backend != "cdev" || c.GPIO.Chip != "", "gpio.chip: must be set")
+	oneOf("analog_discovery.backend", c.AnalogDiscovery.Backend, "libdwf", "simulated")
+	oneOf("uart.transport", c.UART.Transport, "serial", "pty")
+	check(c.UART.Port == "" || c.UART.VID+c.UART.PID+c.UART.SerialNumber == "",
+		"uart.port: can't be combined with uart.vid, uart.pid or uart.serial_number")
+	check(c.UART.VID == "" || usbID.MatchString(c.UART.VID), "uart.vid: %q is not 4 hex digits", c.UART.VID)
+	check(c.UART.PID == "" || usbID.MatchString(c.UART.PID), "uart.pid: %q is not 4 hex digits", c.UART.PID)
+	check(c.UART.DefaultSpeed > 0, "uart.default_speed: must be positive")
+	check(c.UART.BufferSize > 0, "uart.buffer_size: must be positive")
+	oneOf("uart.mode", c.UART.Mode, "line", "raw", "hex")
+	oneOf("uart.line_ending", c.UART.LineEnding, "lf", "crlf", "cr", "none")
+
+	check(len(c.AnalogDiscovery.OutputPins) > 0, "analog_discovery.output_pins: must not be empty")
+	for _, pin := range c.AnalogDiscovery.OutputPins {
+		check(pin >= 0 && pin <= 15, "analog_discovery.output_pins: %d is not a digital I/O pin (0-15)", pin)
+	}
+	check(!hasDuplicates(c.AnalogDiscovery.OutputPins), "analog_discovery.output_pins: pins must be unique")
+	for _, channel := range c.AnalogDiscovery.WavegenChannels {
+		check(channel == 0 || channel == 1, "analog_discovery.wavegen_channels: %d is not a wavegen channel (0-1)", channel)
+	}
+	for _, function := range c.AnalogDiscovery.WavegenFunctions {
+		oneOf("analog_discovery.wavegen_functions", function, "sine", "rampup", "triangle", "pulse")
+	}
+
+	oneOf("potentiometer.backend", c.Potentiometer.Backend, "i2c", "emulated")
+	check(c.Potentiometer.Backend != "i2c" || c.Potentiometer.Bus != "", "potentiometer.bus: must be set")
+	check(c.Potentiometer.Address >= 0x08 && c.Potentiometer.Address <= 0x77, "potentiometer.address: 0x%02X is not a 7-bit I2C address", c.Potentiometer.Address)
+
+	oneOf("camera.source", c.Camera.Source, "v4l2", "file", "test-pattern")
+	check(c.Camera.Source != "file" || c.Camera.File != "", "camera.file: must be set when camera.source is file")
+	check(c.Camera.Width > 0 && c.Camera.Height > 0, "camera.width, camera.height: must be positive")
+	check(c.Camera.FPS > 0, "camera.fps: must be positive")
+
+	check(c.MCU.STFlashPath != "" && c.MCU.STInfoPath != "", "mcu.st_flash_path, mcu.st_info_path: must be set")
+	check(c.FPGA.UrjtagPath != "", "fpga.urjtag_path: must be set")
+	check(c.FPGA.BSDLPath != "", "fpga.bsdl_path: must be set")
+
+	check(len(c.Multiplexer.Mux1Pins) == 2, "multiplexer.mux1_pins: needs exactly 2 pins")
+	check(len(c.Multiplexer.Mux2Pins) == 2, "multiplexer.mux2_pins: needs exactly 2 pins")
+
+	errs = append(errs, c.validatePins()...)
+	return errors.Join(errs...)
+}
+
+// validatePins makes sure every required pin is set and no pin is used twice
+func (c *Config) validatePins() []error {
+	type pin struct {
+		name     string
+		value    int
+		required bool
+	}
+	pins := []pin{
+		{"gpio.power_on_pin", c.GPIO.PowerOnPin, true},
+		{"mcu.boot0_pin", c.MCU.Boot0Pin, false},
+		{"mcu.reset_pin", c.MCU.ResetPin, false},
+		{"fpga.tdi", c.FPGA.TDI, true},
+		{"fpga.tms", c.FPGA.TMS, true},
+		{"fpga.tck", c.FPGA.TCK, true},
+		{"fpga.tdo", c.FPGA.TDO, true},
+	}
+	for i, value := range c.Multiplexer.Mux1Pins {
+		pins = append(pins, pin{fmt.Sprintf("multiplexer.mux1_pins[%d]", i), value, true})
+	}
+	for i, value := range c.Multiplexer.Mux2Pins {
+		pins = append(pins, pin{fmt.Sprintf("multiplexer.mux2_pins[%d]", i), value, true})
+	}
+
+	var errs []error
+	users := map[int][]string{}
+	for _, p := range pins {
+		if p.value == Unset {
+			if p.required {
+				errs = append(errs, fmt.Errorf("%s: must be set", p.name))
+			}
+			continue
+		}
+		if p.value < 0 {
+			errs = append(errs, fmt.Errorf("%s: %d is not a valid pin", p.name, p.value))
+			continue
+		}
+		users[p.value] = append(users[p.value], p.name)
+	}
+
+	duplicated := []int{}
+	for value, names := range users {
+		if len(names) > 1 {
+			duplicated = append(duplicated, value)
+		}
+	}
+	sort.Ints(duplicated)
+	for _, value := range duplicated {
+		errs = append(errs, fmt.Errorf("pin %d is assigned to more than one signal: %s", value, strings.Join(users[value], ", ")))
+	}
+	return errs
+}
+
+func hasDuplicates[T comparable](values []T) bool {
+	seen := map[T]bool{}
+	for _, value := range values {
+		if seen[value] {
+			return true
+		}
+		seen[value] = true
+	}
+	return false
+}
@@ -1,117 +1,318 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"strconv"
+	"reflect"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultPath is where the station configuration is read from unless
+// STATION_CONFIG points somewhere else
+const DefaultPath = "station.yaml"
+
+// Unset marks an optional GPIO pin that is not wired
+const Unset = -1
+
+// Config is the station configuration. Fields tagged with env can be
+// overridden from the environment (or .env), which is meant for secrets that
+// shouldn't live in the config file. The reload tag says how a field may change
+// at runtime, see Diff.
 type Config struct {
-	PORT string
-	BOOT0_PIN int
-	RESET_PIN int
-	TDI int
-	TMS int
-	TCK int
-	TDO int
-	MASTER_SERVER_API_SECRET string
-	MULTIPLEXER_A0_1 int
-	MULTIPLEXER_A0_2 int
-	MULTIPLEXER_A1_1 int
-	MULTIPLEXER_A1_2 int
-	POWER_ON_PIN int
+	Station         StationConfig         `yaml:"station"`
+	Server          ServerConfig          `yaml:"server"`
+	Auth            AuthConfig            `yaml:"auth"`
+	MasterServer    MasterServerConfig    `yaml:"master_server"`
+	Session         SessionConfig         `yaml:"session"`
+	StationReset    StationResetConfig    `yaml:"station_reset"`
+	Audit           AuditConfig           `yaml:"audit"`
+	Peripherals     PeripheralsConfig     `yaml:"peripherals"`
+	GPIO            GPIOConfig            `yaml:"gpio"`
+	Multiplexer     MultiplexerConfig     `yaml:"multiplexer"`
+	MCU             MCUConfig             `yaml:"mcu"`
+	FPGA            FPGAConfig            `yaml:"fpga"`
+	AnalogDiscovery AnalogDiscoveryConfig `yaml:"analog_discovery"`
+	UART            UARTConfig            `yaml:"uart"`
+	Potentiometer   PotentiometerConfig   `yaml:"potentiometer"`
+	Camera          CameraConfig          `yaml:"camera"`
 }
 
-func LoadConfig() (*Config, error) {
+type StationConfig struct {
+	// ID is what signed session tokens must be issued for
+	ID string `yaml:"id" reload:"hot"`
+}
 
-	err := godotenv.Load()
-	if err != nil {
-		return nil, err
-	}
+type ServerConfig struct {
+	Port          int    `yaml:"port" reload:"restart"`
+	UploadDir     string `yaml:"upload_dir" reload:"hot"`
+	MaxUploadSize int64  `yaml:"max_upload_size" reload:"hot"`
+}
 
-	config := &Config{}
-	
-	PORT, err := strconv.Atoi(os.Getenv("PORT"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing PORT: %d %w", PORT, err)
-	}
-	config.PORT = os.Getenv("PORT")
+type AuthConfig struct {
+	MasterServerAPISecret string `yaml:"master_server_api_secret" env:"MASTER_SERVER_API_SECRET" reload:"hot"`
+	// SessionTokenKey is the HMAC key the master server signs session tokens
+	// with. When empty only tokens posted to /api/session are accepted.
+	SessionTokenKey string `yaml:"session_token_key" env:"SESSION_TOKEN_KEY" reload:"hot"`
+}
 
+type SessionConfig struct {
+	// ReconnectWindow is how long a dropped WebSocket may take to come back
+	// before the session is reset
+	ReconnectWindow time.Duration `yaml:"reconnect_window" reload:"hot"`
+	// StateFile keeps the session, reservations and detected board across
+	// restarts. Empty keeps them in memory only.
+	StateFile string `yaml:"state_file" reload:"restart"`
+	// IdleTimeout is how long the student may go without using the station
+	// before being warned, 0 never releases idle sessions. IdleWarning later
+	// the session ends.
+	IdleTimeout time.Duration `yaml:"idle_timeout" reload:"hot"`
+	IdleWarning time.Duration `yaml:"idle_warning" reload:"hot"`
+	// CountdownInterval is how often connected clients get the remaining time, 0 never
+	CountdownInterval time.Duration `yaml:"countdown_interval" reload:"hot"`
+	// ExpiryWarnings are the remaining times at which clients are warned that the session ends
+	ExpiryWarnings []time.Duration `yaml:"expiry_warnings" reload:"hot"`
+}
 
-	BOOT0_PIN, err := strconv.Atoi(os.Getenv("BOOT0_PIN"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing BOOT0_PIN: %w", err)
-	}
-	config.BOOT0_PIN = BOOT0_PIN
+type MasterServerConfig struct {
+	// URL receives the station's events. Empty keeps the station silent.
+	URL string `yaml:"url" reload:"hot"`
+	// WebhookSecret signs the events. When empty the master server API secret is used.
+	WebhookSecret string `yaml:"webhook_secret" env:"WEBHOOK_SECRET" reload:"hot"`
+	// RetryMax caps the backoff between failed deliveries
+	RetryMax time.Duration `yaml:"retry_max" reload:"hot"`
+}
 
+type StationResetConfig struct {
+	// Steps run in this order whenever a session ends: wavegen, outputs,
+	// potentiometer, multiplexer, uart and firmware (reflash the example firmware)
+	Steps []string `yaml:"steps" reload:"hot"`
+}
 
-	RESET_PIN, err := strconv.Atoi(os.Getenv("NRST_PIN"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing NRST_PIN: %w", err)
-	}
-	config.RESET_PIN = RESET_PIN
+type AuditConfig struct {
+	// Dir gets one JSONL file per session with every action of its clients.
+	// Empty turns the audit log off.
+	Dir string `yaml:"dir" reload:"hot"`
+}
 
+type PeripheralsConfig struct {
+	RetryInterval time.Duration `yaml:"retry_interval" reload:"hot"`
+}
 
-	TDI, err := strconv.Atoi(os.Getenv("TDI"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing TDI: %w", err)
-	}
-	config.TDI = TDI
+type GPIOConfig struct {
+	// Backend is "cdev" for the Linux GPIO character device or "fake" to keep levels in memory
+	Backend    string `yaml:"backend" reload:"restart"`
+	Chip       string `yaml:"chip" reload:"restart"`
+	PowerOnPin int    `yaml:"power_on_pin" reload:"idle"`
+}
 
-	TMS, err := strconv.Atoi(os.Getenv("TMS"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing TMS: %w", err)
-	}
-	config.TMS = TMS
+type MultiplexerConfig struct {
+	// Select lines (A0, A1) of each multiplexer
+	Mux1Pins []int `yaml:"mux1_pins" reload:"idle"`
+	Mux2Pins []int `yaml:"mux2_pins" reload:"idle"`
+}
 
-	TCK, err := strconv.Atoi(os.Getenv("TCK"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing TCK: %w", err)
-	}
-	config.TCK = TCK
+type MCUConfig struct {
+	Boot0Pin        int    `yaml:"boot0_pin" reload:"idle"`
+	ResetPin        int    `yaml:"reset_pin" reload:"idle"`
+	STFlashPath     string `yaml:"st_flash_path" reload:"idle"`
+	STInfoPath      string `yaml:"st_info_path" reload:"idle"`
+	ExampleFirmware string `yaml:"example_firmware" reload:"hot"`
+}
 
-	TDO, err := strconv.Atoi(os.Getenv("TDO"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing TDO: %w", err)
-	}
-	config.TDO = TDO
+type FPGAConfig struct {
+	TDI             int    `yaml:"tdi" reload:"idle"`
+	TMS             int    `yaml:"tms" reload:"idle"`
+	TCK             int    `yaml:"tck" reload:"idle"`
+	TDO             int    `yaml:"tdo" reload:"idle"`
+	UrjtagPath      string `yaml:"urjtag_path" reload:"idle"`
+	BSDLPath        string `yaml:"bsdl_path" reload:"idle"`
+	ExampleFirmware string `yaml:"example_firmware" reload:"hot"`
+}
 
-	config.MASTER_SERVER_API_SECRET = os.Getenv("MASTER_SERVER_API_SECRET")
-	if config.MASTER_SERVER_API_SECRET == "" {
-		return nil, fmt.Errorf("MASTER_SERVER_API_SECRET is empty")
-	}
+type AnalogDiscoveryConfig struct {
+	// Backend is "libdwf" for the WaveForms SDK or "simulated"
+	Backend string `yaml:"backend" reload:"restart"`
+	// What clients may drive: digital pins, wavegen channels and wavegen functions
+	OutputPins       []int    `yaml:"output_pins" reload:"idle"`
+	WavegenChannels  []int    `yaml:"wavegen_channels" reload:"idle"`
+	WavegenFunctions []string `yaml:"wavegen_functions" reload:"idle"`
+}
 
-	MULTIPLEXER_A0_1, err := strconv.Atoi(os.Getenv("MULTIPLEXER_A0_1"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing MULTIPLEXER_A0_1: %w", err)
+type UARTConfig struct {
+	// Transport is "serial" for a serial port or "pty" for an in-process echo MCU
+	Transport string `yaml:"transport" reload:"restart"`
+	// Port is the serial port to open, best a stable /dev/serial/by-id path.
+	// Otherwise the port is the USB device with VID, PID and SerialNumber (those
+	// set), or the first one the system reports when nothing is set.
+	Port         string `yaml:"port" reload:"restart"`
+	VID          string `yaml:"vid" reload:"restart"`
+	PID          string `yaml:"pid" reload:"restart"`
+	SerialNumber string `yaml:"serial_number" reload:"restart"`
+	// DefaultSpeed is the baud rate the port is (re)opened with
+	DefaultSpeed int `yaml:"default_speed" reload:"hot"`
+	// BufferSize is how many bytes of output are kept for clients that reconnect
+	BufferSize int `yaml:"buffer_size" reload:"hot"`
+	// Mode is how clients talk to the UART unless they pick another one: "line"
+	// adds LineEnding (lf, crlf, cr or none) to what they send, "raw" sends it
+	// as is and "hex" takes hex strings
+	Mode       string `yaml:"mode" reload:"hot"`
+	LineEnding string `yaml:"line_ending" reload:"hot"`
+}
+
+type PotentiometerConfig struct {
+	// Backend is "i2c" for a MAX5395 on Bus or "emulated" for an in-memory one
+	Backend string `yaml:"backend" reload:"restart"`
+	Bus     string `yaml:"bus" reload:"idle"`
+	Address uint16 `yaml:"address" reload:"idle"`
+}
+
+type CameraConfig struct {
+	// Source is "v4l2", "file" to loop File (JPEG directory or MJPEG file) or "test-pattern"
+	Source string `yaml:"source" reload:"idle"`
+	// Device is the V4L2 device. When empty the first working /dev/videoN is used.
+	Device string `yaml:"device" reload:"idle"`
+	File   string `yaml:"file" reload:"idle"`
+	Width  int    `yaml:"width" reload:"idle"`
+	Height int    `yaml:"height" reload:"idle"`
+	FPS    int    `yaml:"fps" reload:"idle"`
+}
+
+// Default returns the configuration used for everything the file leaves out
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:          8080,
+			UploadDir:     "./uploads",
+			MaxUploadSize: 10 * (10 << 20), // 100 MB
+		},
+		Session: SessionConfig{
+			ReconnectWindow:   6 * time.Second,
+			StateFile:         "./station-state.json",
+			IdleWarning:       time.Minute,
+			CountdownInterval: 30 * time.Second,
+			ExpiryWarnings:    []time.Duration{10 * time.Minute, 2 * time.Minute},
+		},
+		MasterServer: MasterServerConfig{
+			RetryMax: 5 * time.Minute,
+		},
+		StationReset: StationResetConfig{
+			Steps: []string{"wavegen", "outputs", "potentiometer", "multiplexer", "uart"},
+		},
+		Audit: AuditConfig{
+			Dir: "./audit",
+		},
+		Peripherals: PeripheralsConfig{
+			RetryInterval: 10 * time.Second,
+		},
+		GPIO: GPIOConfig{
+			Backend:    "cdev",
+			Chip:       "/dev/gpiochip0",
+			PowerOnPin: Unset,
+		},
+		MCU: MCUConfig{
+			Boot0Pin:        Unset,
+			ResetPin:        Unset,
+			STFlashPath:     "st-flash",
+			STInfoPath:      "st-info",
+			ExampleFirmware: "/home/pi/digitrans-lab-go/example-firmware/new-mcu-3.hex",
+		},
+		FPGA: FPGAConfig{
+			TDI:             Unset,
+			TMS:             Unset,
+			TCK:             Unset,
+			TDO:             Unset,
+			UrjtagPath:      "/home/pi/urjtag-2021.03/src/apps/jtag/jtag",
+			BSDLPath:        "/home/pi/EP4CE10E22.bsdl",
+			ExampleFirmware: "/home/pi/digitrans-lab-go/example-firmware/fpga.svf",
+		},
+		AnalogDiscovery: AnalogDiscoveryConfig{
+			Backend:          "libdwf",
+			OutputPins:       []int{12, 13, 14, 15},
+			WavegenChannels:  []int{0, 1},
+			WavegenFunctions: []string{"sine", "rampup", "triangle", "pulse"},
+		},
+		UART: UARTConfig{
+			Transport:    "serial",
+			DefaultSpeed: 9600,
+			BufferSize:   256 << 10, // 256 KB
+			Mode:         "line",
+			LineEnding:   "lf",
+		},
+		Potentiometer: PotentiometerConfig{
+			Backend: "i2c",
+			Bus:     "/dev/i2c-1",
+			Address: 0x28,
+		},
+		Camera: CameraConfig{
+			Source: "v4l2",
+			Width:  1920,
+			Height: 1080,
+			FPS:    15,
+		},
 	}
-	config.MULTIPLEXER_A0_1 = MULTIPLEXER_A0_1
+}
 
-	MULTIPLEXER_A0_2, err := strconv.Atoi(os.Getenv("MULTIPLEXER_A0_2"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing MULTIPLEXER_A0_2: %w", err)
+// Path returns the configuration file to load
+func Path() string {
+	if path := os.Getenv("STATION_CONFIG"); path != "" {
+		return path
 	}
-	config.MULTIPLEXER_A0_2 = MULTIPLEXER_A0_2
+	return DefaultPath
+}
 
-	MULTIPLEXER_A1_1, err := strconv.Atoi(os.Getenv("MULTIPLEXER_A1_1"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing MULTIPLEXER_A1_1: %w", err)
+func LoadConfig() (*Config, error) {
+	return Load(Path())
+}
+
+// Load reads the configuration file at path on top of the defaults, applies
+// environment overrides and validates the result
+func Load(path string) (*Config, error) {
+	// .env is optional now and only carries secrets
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error loading .env: %w", err)
 	}
-	config.MULTIPLEXER_A1_1 = MULTIPLEXER_A1_1
 
-	MULTIPLEXER_A1_2, err := strconv.Atoi(os.Getenv("MULTIPLEXER_A1_2"))
+	config := Default()
+
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("Error parsing MULTIPLEXER_A1_2: %w", err)
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
 	}
-	config.MULTIPLEXER_A1_2 = MULTIPLEXER_A1_2
 
-	POWER_ON_PIN, err := strconv.Atoi(os.Getenv("POWER_ON_PIN"))
-	if err != nil {
-		return nil, fmt.Errorf("Error parsing POWER_ON_PIN: %w", err)
+	applyEnvOverrides(reflect.ValueOf(&config).Elem())
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", path, err)
 	}
-	config.POWER_ON_PIN = POWER_ON_PIN
+	return &config, nil
+}
 
-	return config, nil
-}
\ No newline at end of file
+// applyEnvOverrides replaces string fields tagged with env by the variable's value when it is set
+func applyEnvOverrides(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			applyEnvOverrides(field)
+			continue
+		}
+		name := v.Type().Field(i).Tag.Get("env")
+		if name == "" || field.Kind() != reflect.String {
+			continue
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			field.SetString(value)
+		}
+	}
+}
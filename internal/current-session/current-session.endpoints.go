@@ -3,6 +3,7 @@ package currentsession
 import (
 	"digitrans-lab-go/internal/config"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 type CreateSessionRequest struct {
 	Token string `json:"token"`
 	SessionEndTime string `json:"sessionEndTime"`
+	ObserverToken string `json:"observerToken"`
 }
 func HandleCreateSession(cfg config.Config, createdCb func(), overwrittenCb func()) func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -29,33 +31,88 @@ func HandleCreateSession(cfg config.Config, createdCb func(), overwrittenCb func
 			return
 		}
 
+		if request.ObserverToken != "" && request.ObserverToken == request.Token {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "observerToken must differ from token"})
+			return
+		}
+
 		session := GetCurrentSession()
-		isOverwritten := session.Set(request.Token, parsedTime)
+		// The previous session has to be torn down before the new one is set,
+		// tearing it down resets the current session
+		isOverwritten := session.IsActive()
+		if isOverwritten {
+			overwrittenCb()
+		}
+		session.Set(request.Token, parsedTime)
+		session.SetObserverToken(request.ObserverToken)
 		createdCb()
 
 		if isOverwritten {
-			overwrittenCb()
-			c.JSON(http.StatusCreated, gin.H{"message": "Session overwritten"})
+			c.JSON(http.StatusCreated, gin.H{"message": "Session overwritten", "id": session.ID()})
 			return
 		}
-		c.JSON(http.StatusCreated, gin.H{"message": "Successfully created"})
+		c.JSON(http.StatusCreated, gin.H{"message": "Successfully created", "id": session.ID()})
 	}
 }
 
 func HandleGetSession(cfg config.Config) func(c *gin.Context) {
 	return func(c *gin.Context) {
 
-		session := GetCurrentSession()
+		state := GetCurrentSession().State()
 		var res gin.H
-		if !session.isActive {
-			res = gin.H{"token": nil, "sessionEndTime": nil}
+		if state == nil {
+			res = gin.H{"id": nil, "token": nil, "sessionEndTime": nil}
 		} else {
-			res = gin.H{"token": session.Token, "sessionEndTime": session.SessionEndTime}
+			res = gin.H{"id": state.ID, "token": state.Token, "sessionEndTime": state.SessionEndTime}
 		}
 		c.JSON(http.StatusOK, res)
 	}
 }
 
+type ExtendSessionRequest struct {
+	SessionEndTime string `json:"sessionEndTime"`
+}
+
+// HandleExtendSession moves the end of the active session without ending it
+func HandleExtendSession(schedule *Schedule, cb func()) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var request ExtendSessionRequest
+		if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		parsedTime, err := time.Parse(time.RFC3339, request.SessionEndTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+			return
+		}
+		if !parsedTime.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sessionEndTime is in the past"})
+			return
+		}
+
+		session := GetCurrentSession()
+		if !session.IsActive() {
+			c.JSON(http.StatusConflict, gin.H{"error": "No active session"})
+			return
+		}
+		// Also covers sessions started without a reservation of their own
+		if schedule.Reserved(session.Token(), time.Now(), parsedTime) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The station is reserved before sessionEndTime"})
+			return
+		}
+		if err := schedule.MoveActiveEnd(session.Token(), parsedTime); err != nil {
+			reservationError(c, err)
+			return
+		}
+		session.SetEndTime(parsedTime)
+		cb()
+
+		c.JSON(http.StatusOK, gin.H{"token": session.Token(), "sessionEndTime": session.SessionEndTime()})
+	}
+}
+
 func HandleDeleteSession(cfg config.Config, cb func()) func(c *gin.Context) {
 	return func(c *gin.Context) {
 
@@ -71,4 +128,101 @@ func HandleDeleteSession(cfg config.Config, cb func()) func(c *gin.Context) {
 
 		c.JSON(http.StatusOK, gin.H{"message": "Session has already been not active"})
 	}
-}
\ No newline at end of file
+}
+type ReservationRequest struct {
+	ID            string `json:"id"`
+	Token         string `json:"token"`
+	ObserverToken string `json:"observerToken"`
+	StartTime     string `json:"startTime"`
+	EndTime       string `json:"endTime"`
+}
+
+func parseReservationTimes(startTime, endTime string) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := time.Parse(time.RFC3339, endTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+func reservationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrReservationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrReservationOverlaps), errors.Is(err, ErrReservationInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func HandleListReservations(schedule *Schedule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"reservations": schedule.List()})
+	}
+}
+
+// HandleAddReservations books a list of reservations, e.g. a whole day at once.
+// Either all of them are booked or none.
+func HandleAddReservations(schedule *Schedule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var request []ReservationRequest
+		if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		reservations := make([]Reservation, len(request))
+		for i, r := range request {
+			start, end, err := parseReservationTimes(r.StartTime, r.EndTime)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+				return
+			}
+			reservations[i] = Reservation{ID: r.ID, Token: r.Token, ObserverToken: r.ObserverToken, StartTime: start, EndTime: end}
+		}
+
+		added, err := schedule.Add(reservations)
+		if err != nil {
+			reservationError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"reservations": added})
+	}
+}
+
+func HandleCancelReservation(schedule *Schedule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if err := schedule.Cancel(c.Param("id")); err != nil {
+			reservationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Reservation cancelled"})
+	}
+}
+
+func HandleRescheduleReservation(schedule *Schedule) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var request ReservationRequest
+		if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		start, end, err := parseReservationTimes(request.StartTime, request.EndTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+			return
+		}
+
+		reservation, err := schedule.Reschedule(c.Param("id"), start, end)
+		if err != nil {
+			reservationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, reservation)
+	}
+}
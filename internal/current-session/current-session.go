@@ -1,16 +1,35 @@
 package currentsession
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// CurrentSession is shared by the HTTP handlers, the WebSocket handlers, the
+// schedule and the timers, so every field is read and written under mu
 type CurrentSession struct {
+	mu sync.Mutex
 	isActive bool
-	SessionEndTime time.Time
-	Token string
+	// id names the session in logs without giving away its token
+	id string
+	sessionEndTime time.Time
+	token string
+	// observerToken lets a teacher watch the session without being able to change anything
+	observerToken string
+	// onChange runs after the session was set, moved or reset, without mu held
+	onChange func()
+}
+
+// SessionState is the part of the session that is kept across restarts
+type SessionState struct {
+	ID             string    `json:"id"`
+	Token          string    `json:"token"`
+	ObserverToken  string    `json:"observerToken,omitempty"`
+	SessionEndTime time.Time `json:"sessionEndTime"`
 }
 
 var (
@@ -19,9 +38,37 @@ var (
 )
 
 func (c *CurrentSession) IsActive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.isActive
 }
 
+// ID names the session, it stays set after the session ended
+func (c *CurrentSession) ID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id
+}
+
+func (c *CurrentSession) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+func (c *CurrentSession) SessionEndTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionEndTime
+}
+
+// HasToken reports whether token belongs to the active session, whatever its end time
+func (c *CurrentSession) HasToken(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isActive && c.token == token
+}
+
 func GetCurrentSession() *CurrentSession {
 	once.Do(func() {
 		instance = &CurrentSession{isActive: false}
@@ -31,18 +78,25 @@ func GetCurrentSession() *CurrentSession {
 
 // Returns true if the session was active before the reset
 func (c *CurrentSession) Reset() bool {
-	ret := true
-
-	if !c.isActive {
-		ret = false
-	}
+	c.mu.Lock()
+	ret := c.isActive
 	c.isActive = false
+	c.mu.Unlock()
+	c.changed()
 
 	return ret
 }
 
 func (c *CurrentSession) ValidateToken(token string) bool {
-	return c.isActive && c.Token == token && time.Now().Before(c.SessionEndTime)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isActive && c.token == token && time.Now().Before(c.sessionEndTime)
+}
+
+func (c *CurrentSession) ValidateObserverToken(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isActive && c.observerToken != "" && c.observerToken == token && time.Now().Before(c.sessionEndTime)
 }
 
 func (c *CurrentSession) ValidateTokenHttpHeader(ctx *gin.Context) bool {
@@ -55,17 +109,103 @@ func (c *CurrentSession) ValidateTokenHttpQuery(ctx *gin.Context) bool {
 
 // Returns true if the session was overwritten
 func (c *CurrentSession) Set(token string, sessionEndTime time.Time) bool {
+	c.mu.Lock()
+	ret := c.isActive
+	c.set(token, sessionEndTime)
+	c.mu.Unlock()
+	c.changed()
 
-	ret := false
+	return ret
+}
 
+// SetIfIdle starts a session only if none is active, checked and set at once.
+// Returns false if a session is active.
+func (c *CurrentSession) SetIfIdle(token string, sessionEndTime time.Time) bool {
+	c.mu.Lock()
 	if c.isActive {
-		c.Reset()
-		ret = true
+		c.mu.Unlock()
+		return false
 	}
+	c.set(token, sessionEndTime)
+	c.mu.Unlock()
+	c.changed()
+	return true
+}
 
+// set must be called with mu held
+func (c *CurrentSession) set(token string, sessionEndTime time.Time) {
 	c.isActive = true
-	c.Token = token
-	c.SessionEndTime = sessionEndTime
+	c.id = newSessionID()
+	c.token = token
+	c.observerToken = ""
+	c.sessionEndTime = sessionEndTime
+}
 
-	return ret
-}
\ No newline at end of file
+func (c *CurrentSession) SetObserverToken(token string) {
+	c.mu.Lock()
+	c.observerToken = token
+	c.mu.Unlock()
+	c.changed()
+}
+
+// SetEndTime moves the end of the active session without touching its token.
+// Returns false if there is no active session.
+func (c *CurrentSession) SetEndTime(sessionEndTime time.Time) bool {
+	c.mu.Lock()
+	if !c.isActive {
+		c.mu.Unlock()
+		return false
+	}
+	c.sessionEndTime = sessionEndTime
+	c.mu.Unlock()
+	c.changed()
+	return true
+}
+
+// SetOnChange registers fn to run whenever the session changes
+func (c *CurrentSession) SetOnChange(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = fn
+}
+
+func (c *CurrentSession) changed() {
+	c.mu.Lock()
+	onChange := c.onChange
+	c.mu.Unlock()
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// State returns the active session, or nil if there is none
+func (c *CurrentSession) State() *SessionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.isActive {
+		return nil
+	}
+	return &SessionState{ID: c.id, Token: c.token, ObserverToken: c.observerToken, SessionEndTime: c.sessionEndTime}
+}
+
+// Restore makes a session saved before a restart active again
+func (c *CurrentSession) Restore(state SessionState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isActive = true
+	c.id = state.ID
+	c.token = state.Token
+	c.observerToken = state.ObserverToken
+	c.sessionEndTime = state.SessionEndTime
+}
+
+// newSessionID sorts by start time, which makes audit logs easy to browse
+func newSessionID() string {
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + randomHex(4)
+}
+
+func randomHex(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
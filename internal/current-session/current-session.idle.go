@@ -0,0 +1,83 @@
+package currentsession
+
+import (
+	"context"
+	"digitrans-lab-go/internal/config"
+	"sync"
+	"time"
+)
+
+// IdleMonitor ends sessions whose student stopped using the station, so a
+// closed laptop lid doesn't keep it booked until the end time. Activity is
+// reported with Touch. After session.idle_timeout without any, the student is
+// warned and session.idle_warning later the session is released.
+type IdleMonitor struct {
+	mu           sync.Mutex
+	cfg          *config.Live
+	lastActivity time.Time
+	warned       bool
+	// warn runs once per idle period, with the time the session will be released at
+	warn func(releaseAt time.Time)
+	// release ends the idle session
+	release func()
+}
+
+func NewIdleMonitor(cfg *config.Live, warn func(releaseAt time.Time), release func()) *IdleMonitor {
+	return &IdleMonitor{
+		cfg:          cfg,
+		lastActivity: time.Now(),
+		warn:         warn,
+		release:      release,
+	}
+}
+
+// Touch records activity of the student
+func (m *IdleMonitor) Touch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastActivity = time.Now()
+	m.warned = false
+}
+
+// Watch checks for idle sessions every second until ctx is done
+func (m *IdleMonitor) Watch(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.check(now)
+		}
+	}
+}
+
+func (m *IdleMonitor) check(now time.Time) {
+	settings := m.cfg.Get().Session
+	if settings.IdleTimeout == 0 || !GetCurrentSession().IsActive() {
+		return
+	}
+
+	m.mu.Lock()
+	warnAt := m.lastActivity.Add(settings.IdleTimeout)
+	releaseAt := warnAt.Add(settings.IdleWarning)
+	var warn, release bool
+	switch {
+	case !now.Before(releaseAt):
+		release = true
+		// Whoever starts the next session gets a fresh idle period
+		m.lastActivity = now
+		m.warned = false
+	case !now.Before(warnAt) && !m.warned:
+		warn = true
+		m.warned = true
+	}
+	m.mu.Unlock()
+
+	if release {
+		m.release()
+	} else if warn {
+		m.warn(releaseAt)
+	}
+}
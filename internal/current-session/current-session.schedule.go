@@ -0,0 +1,340 @@
+package currentsession
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrReservationNotFound   = errors.New("reservation not found")
+	ErrReservationOverlaps   = errors.New("reservation overlaps another one")
+	ErrReservationInProgress = errors.New("reservation is in progress")
+)
+
+// Reservation books the station for the holder of Token between StartTime and EndTime
+type Reservation struct {
+	ID            string    `json:"id"`
+	Token         string    `json:"token"`
+	ObserverToken string    `json:"observerToken,omitempty"`
+	StartTime     time.Time `json:"startTime"`
+	EndTime       time.Time `json:"endTime"`
+}
+
+func (r Reservation) overlaps(other Reservation) bool {
+	return r.StartTime.Before(other.EndTime) && other.StartTime.Before(r.EndTime)
+}
+
+func (r Reservation) validate(now time.Time) error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	if r.ObserverToken == r.Token {
+		return errors.New("observerToken must differ from token")
+	}
+	if !r.EndTime.After(r.StartTime) {
+		return errors.New("endTime must be after startTime")
+	}
+	if !r.EndTime.After(now) {
+		return errors.New("endTime is in the past")
+	}
+	return nil
+}
+
+// Schedule holds the upcoming reservations and turns each one into the current
+// session at its start time
+type Schedule struct {
+	mu           sync.Mutex
+	reservations []Reservation // sorted by StartTime, never overlapping
+	activeID     string
+	timer        *time.Timer
+	generation   int
+	// preempt kicks out the holder of the current session before another reservation starts
+	preempt func()
+	// started runs once a reservation has become the current session
+	started func(Reservation)
+	// cancelled runs when the reservation holding the current session is cancelled
+	cancelled func(Reservation)
+	// onChange runs after the reservations changed
+	onChange func()
+}
+
+func NewSchedule(preempt func(), started func(Reservation), cancelled func(Reservation)) *Schedule {
+	return &Schedule{
+		preempt:   preempt,
+		started:   started,
+		cancelled: cancelled,
+	}
+}
+
+// List returns the reservations that haven't ended yet, including the one in progress
+func (s *Schedule) List() []Reservation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	return slices.Clone(s.reservations)
+}
+
+// Reserved reports whether a reservation for another token falls between from
+// and to, so the station mustn't be handed to anyone else then
+func (s *Schedule) Reserved(token string, from, to time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	window := Reservation{StartTime: from, EndTime: to}
+	return slices.ContainsFunc(s.reservations, func(r Reservation) bool {
+		return r.Token != token && r.overlaps(window)
+	})
+}
+
+// Add books all reservations or none of them. Reservations without an ID get one.
+func (s *Schedule) Add(reservations []Reservation) ([]Reservation, error) {
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	added := make([]Reservation, 0, len(reservations))
+	for i, r := range reservations {
+		if err := r.validate(now); err != nil {
+			return nil, fmt.Errorf("reservation %d: %w", i, err)
+		}
+		if r.ID == "" {
+			r.ID = newReservationID()
+		}
+		if s.indexOf(r.ID) >= 0 || slices.ContainsFunc(added, func(other Reservation) bool { return other.ID == r.ID }) {
+			return nil, fmt.Errorf("reservation %d: id %q is already taken", i, r.ID)
+		}
+		for _, other := range append(slices.Clone(s.reservations), added...) {
+			if r.overlaps(other) {
+				return nil, fmt.Errorf("%w: %s", ErrReservationOverlaps, other.ID)
+			}
+		}
+		added = append(added, r)
+	}
+
+	s.reservations = append(s.reservations, added...)
+	s.sort()
+	s.arm()
+	return added, nil
+}
+
+// Cancel removes a reservation. Cancelling the one in progress ends its session.
+func (s *Schedule) Cancel(id string) error {
+	s.mu.Lock()
+	i := s.indexOf(id)
+	if i < 0 {
+		s.mu.Unlock()
+		return ErrReservationNotFound
+	}
+	r := s.reservations[i]
+	s.reservations = slices.Delete(s.reservations, i, i+1)
+	wasActive := s.activeID == id
+	if wasActive {
+		s.activeID = ""
+	}
+	s.arm()
+	s.mu.Unlock()
+	s.changed()
+
+	if wasActive && s.holdsCurrentSession(r) {
+		s.cancelled(r)
+	}
+	return nil
+}
+
+// Reschedule moves a reservation that hasn't started yet
+func (s *Schedule) Reschedule(id string, startTime, endTime time.Time) (Reservation, error) {
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	i := s.indexOf(id)
+	if i < 0 {
+		return Reservation{}, ErrReservationNotFound
+	}
+	if s.activeID == id {
+		return Reservation{}, ErrReservationInProgress
+	}
+
+	r := s.reservations[i]
+	r.StartTime = startTime
+	r.EndTime = endTime
+	if err := r.validate(now); err != nil {
+		return Reservation{}, err
+	}
+	for j, other := range s.reservations {
+		if j != i && r.overlaps(other) {
+			return Reservation{}, fmt.Errorf("%w: %s", ErrReservationOverlaps, other.ID)
+		}
+	}
+
+	s.reservations[i] = r
+	s.sort()
+	s.arm()
+	return r, nil
+}
+
+// MoveActiveEnd moves the end of the reservation in progress when it belongs to
+// token, so the schedule keeps matching the session. It fails if the new end
+// runs into the next reservation.
+func (s *Schedule) MoveActiveEnd(token string, endTime time.Time) error {
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	i := s.indexOf(s.activeID)
+	if i < 0 || s.reservations[i].Token != token {
+		return nil
+	}
+
+	r := s.reservations[i]
+	r.EndTime = endTime
+	for j, other := range s.reservations {
+		if j != i && r.overlaps(other) {
+			return fmt.Errorf("%w: %s", ErrReservationOverlaps, other.ID)
+		}
+	}
+	s.reservations[i] = r
+	return nil
+}
+
+// ReleaseActive drops the reservation in progress when it belongs to token, so
+// the rest of its slot can be booked again once its session was released early.
+// It returns the reservation as it was booked.
+func (s *Schedule) ReleaseActive(token string) (Reservation, bool) {
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	i := s.indexOf(s.activeID)
+	if i < 0 || s.reservations[i].Token != token {
+		return Reservation{}, false
+	}
+
+	r := s.reservations[i]
+	s.reservations = slices.Delete(s.reservations, i, i+1)
+	s.activeID = ""
+	s.arm()
+	return r, true
+}
+
+// SetOnChange registers fn to run whenever the reservations change. It runs
+// without the schedule locked, so fn may call Snapshot.
+func (s *Schedule) SetOnChange(fn func()) {
+	s.onChange = fn
+}
+
+func (s *Schedule) changed() {
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// Snapshot returns the reservations and the ID of the one in progress, for saving
+func (s *Schedule) Snapshot() ([]Reservation, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	return slices.Clone(s.reservations), s.activeID
+}
+
+// Restore brings back reservations saved before a restart. Those whose start
+// passed while the backend was down are started right away.
+func (s *Schedule) Restore(reservations []Reservation, activeID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reservations = slices.Clone(reservations)
+	s.activeID = activeID
+	s.sort()
+	s.arm()
+}
+
+// arm schedules the activation of the next reservation. Callers hold mu.
+func (s *Schedule) arm() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.generation++
+
+	now := time.Now()
+	s.prune(now)
+	next := slices.IndexFunc(s.reservations, func(r Reservation) bool { return r.ID != s.activeID })
+	if next < 0 {
+		return
+	}
+
+	generation := s.generation
+	s.timer = time.AfterFunc(s.reservations[next].StartTime.Sub(now), func() {
+		s.activate(generation)
+	})
+}
+
+func (s *Schedule) activate(generation int) {
+	s.mu.Lock()
+	if generation != s.generation {
+		// The schedule changed since this timer was armed
+		s.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	s.prune(now)
+	next := slices.IndexFunc(s.reservations, func(r Reservation) bool { return r.ID != s.activeID })
+	if next < 0 || s.reservations[next].StartTime.After(now) {
+		s.arm()
+		s.mu.Unlock()
+		return
+	}
+	r := s.reservations[next]
+	s.activeID = r.ID
+	s.arm()
+	s.mu.Unlock()
+	s.changed()
+
+	fmt.Println("Starting reserved session", r.ID, "until", r.EndTime)
+	session := GetCurrentSession()
+	if session.IsActive() {
+		s.preempt()
+	}
+	session.Set(r.Token, r.EndTime)
+	session.SetObserverToken(r.ObserverToken)
+	s.started(r)
+}
+
+// prune drops reservations that have ended. Callers hold mu.
+func (s *Schedule) prune(now time.Time) {
+	s.reservations = slices.DeleteFunc(s.reservations, func(r Reservation) bool {
+		return !r.EndTime.After(now)
+	})
+	if s.indexOf(s.activeID) < 0 {
+		s.activeID = ""
+	}
+}
+
+func (s *Schedule) sort() {
+	slices.SortFunc(s.reservations, func(a, b Reservation) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+}
+
+func (s *Schedule) indexOf(id string) int {
+	return slices.IndexFunc(s.reservations, func(r Reservation) bool { return r.ID == id })
+}
+
+func (s *Schedule) holdsCurrentSession(r Reservation) bool {
+	session := GetCurrentSession()
+	return session.HasToken(r.Token)
+}
+
+func newReservationID() string {
+	return randomHex(8)
+}
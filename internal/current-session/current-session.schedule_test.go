@@ -0,0 +1,36 @@
+package currentsession
+
+import (
+	"testing"
+	"time"
+)
+
+// Releasing the reservation in progress frees the rest of its slot, but only
+// for the token that holds it
+func TestReleaseActiveFreesSlot(t *testing.T) {
+	now := time.Now()
+	schedule := NewSchedule(func() {}, func(Reservation) {}, func(Reservation) {})
+	active := Reservation{ID: "r1", Token: "booked", StartTime: now.Add(-10 * time.Minute), EndTime: now.Add(time.Hour)}
+	schedule.Restore([]Reservation{active}, active.ID)
+
+	if _, ok := schedule.ReleaseActive("someone-else"); ok {
+		t.Fatal("released a reservation held by another token")
+	}
+	if !schedule.Reserved("other", now, now.Add(30*time.Minute)) {
+		t.Fatal("the slot is free before the release")
+	}
+
+	released, ok := schedule.ReleaseActive("booked")
+	if !ok || released.ID != active.ID || !released.EndTime.Equal(active.EndTime) {
+		t.Fatalf("ReleaseActive = %+v, %v, want the active reservation", released, ok)
+	}
+	if schedule.Reserved("other", now, now.Add(30*time.Minute)) {
+		t.Error("the rest of the slot is still reserved")
+	}
+	if _, err := schedule.Add([]Reservation{{Token: "next", StartTime: now.Add(time.Minute), EndTime: now.Add(30 * time.Minute)}}); err != nil {
+		t.Errorf("booking the freed slot: %v", err)
+	}
+	if _, ok := schedule.ReleaseActive("booked"); ok {
+		t.Error("released the same reservation twice")
+	}
+}
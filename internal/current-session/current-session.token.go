@@ -0,0 +1,175 @@
+package currentsession
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"digitrans-lab-go/internal/config"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles of session clients. Observers may watch the session but not change anything.
+const (
+	RoleStudent  = "student"
+	RoleObserver = "observer"
+)
+
+// tokenLeeway absorbs clock drift between the master server and the station
+const tokenLeeway = 30 * time.Second
+
+// TokenClaims is the payload of a session token signed by the master server
+type TokenClaims struct {
+	StationID string `json:"station"`
+	Role      string `json:"role"`
+	Subject   string `json:"sub,omitempty"`
+	NotBefore int64  `json:"nbf"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// ParseToken verifies a JWT signed with HS256 and checks that it was issued for
+// stationID and that now is inside its session window
+func ParseToken(token string, key []byte, stationID string, now time.Time) (TokenClaims, error) {
+	var claims TokenClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return claims, fmt.Errorf("malformed token header: %w", err)
+	}
+	if header.Alg != "HS256" {
+		return claims, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("malformed token signature: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return claims, errors.New("invalid token signature")
+	}
+
+	if err := decodeTokenPart(parts[1], &claims); err != nil {
+		return claims, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if claims.StationID != stationID {
+		return claims, fmt.Errorf("token is for station %q", claims.StationID)
+	}
+	if claims.Role != RoleStudent && claims.Role != RoleObserver {
+		return claims, fmt.Errorf("unknown role %q", claims.Role)
+	}
+	if claims.ExpiresAt == 0 || !now.Before(claims.Expiry().Add(tokenLeeway)) {
+		return claims, errors.New("token has expired")
+	}
+	if now.Add(tokenLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return claims, errors.New("token is not valid yet")
+	}
+	return claims, nil
+}
+
+func (c TokenClaims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+func decodeTokenPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// TokenValidator authorizes clients. It accepts the tokens of the current session
+// and, when a key is configured, tokens signed by the master server. A signed
+// student token starts its session if the station is free and not reserved for
+// someone else, so a student isn't locked out when the POST to /api/session was
+// lost or the station restarted.
+type TokenValidator struct {
+	mu       sync.Mutex
+	cfg      *config.Live
+	schedule *Schedule
+	// started runs after a signed token started its session
+	started func()
+	// Signed tokens that already started a session, with their expiry. Once that
+	// session ends the token has to match the current session like any other.
+	adopted map[string]time.Time
+}
+
+func NewTokenValidator(cfg *config.Live, schedule *Schedule, started func()) *TokenValidator {
+	return &TokenValidator{
+		cfg:      cfg,
+		schedule: schedule,
+		started:  started,
+		adopted:  map[string]time.Time{},
+	}
+}
+
+// Authorize returns the role token grants
+func (v *TokenValidator) Authorize(token string) (string, bool) {
+	session := GetCurrentSession()
+	if session.ValidateToken(token) {
+		return RoleStudent, true
+	}
+	if session.ValidateObserverToken(token) {
+		return RoleObserver, true
+	}
+
+	cfg := v.cfg.Get()
+	if cfg.Auth.SessionTokenKey == "" || token == "" {
+		return "", false
+	}
+	now := time.Now()
+	claims, err := ParseToken(token, []byte(cfg.Auth.SessionTokenKey), cfg.Station.ID, now)
+	if err != nil {
+		return "", false
+	}
+	if claims.Role == RoleObserver {
+		return RoleObserver, true
+	}
+
+	if v.schedule.Reserved(token, now, claims.Expiry()) {
+		fmt.Println("Not starting a session from the signed token of", claims.Subject, "as the station is reserved")
+		return "", false
+	}
+
+	v.mu.Lock()
+	for adopted, expiry := range v.adopted {
+		if now.After(expiry) {
+			delete(v.adopted, adopted)
+		}
+	}
+	_, adopted := v.adopted[token]
+	if adopted || !session.SetIfIdle(token, claims.Expiry()) {
+		v.mu.Unlock()
+		return "", false
+	}
+	v.adopted[token] = claims.Expiry()
+	v.mu.Unlock()
+
+	// Starting the station takes a while, other clients are authorized meanwhile
+	fmt.Println("Started session from signed token for", claims.Subject, "until", claims.Expiry())
+	v.started()
+	return RoleStudent, true
+}
+
+func (v *TokenValidator) AuthorizeHttpHeader(ctx *gin.Context) (string, bool) {
+	return v.Authorize(ctx.Request.Header.Get("Authorization"))
+}
+
+func (v *TokenValidator) AuthorizeHttpQuery(ctx *gin.Context) (string, bool) {
+	return v.Authorize(ctx.Query("token"))
+}
@@ -0,0 +1,79 @@
+package currentsession
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"digitrans-lab-go/internal/config"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func signToken(t *testing.T, key string, claims TokenClaims) string {
+	t.Helper()
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	unsigned := encode(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newTestValidator(schedule *Schedule, started func()) *TokenValidator {
+	cfg := config.Default()
+	cfg.Auth.SessionTokenKey = "key"
+	cfg.Station.ID = "lab-1"
+	return NewTokenValidator(config.NewLive("", &cfg), schedule, started)
+}
+
+func TestSignedTokenStartsSessionOnFreeStation(t *testing.T) {
+	GetCurrentSession().Reset()
+	t.Cleanup(func() { GetCurrentSession().Reset() })
+
+	now := time.Now()
+	token := signToken(t, "key", TokenClaims{StationID: "lab-1", Role: RoleStudent, NotBefore: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	started := 0
+	validator := newTestValidator(NewSchedule(func() {}, func(Reservation) {}, func(Reservation) {}), func() {
+		started++
+	})
+
+	if role, ok := validator.Authorize(token); !ok || role != RoleStudent {
+		t.Fatalf("Authorize = %q, %v, want the student role", role, ok)
+	}
+	if started != 1 {
+		t.Fatalf("started ran %d times, want once", started)
+	}
+	if !GetCurrentSession().ValidateToken(token) {
+		t.Fatal("the signed token didn't become the current session")
+	}
+	// The session is running now, so the token is accepted without starting it again
+	if _, ok := validator.Authorize(token); !ok || started != 1 {
+		t.Fatalf("second Authorize: ok = %v, started = %d", ok, started)
+	}
+}
+
+func TestSignedTokenDoesNotTakeReservedStation(t *testing.T) {
+	GetCurrentSession().Reset()
+	t.Cleanup(func() { GetCurrentSession().Reset() })
+
+	now := time.Now()
+	schedule := NewSchedule(func() {}, func(Reservation) {}, func(Reservation) {})
+	if _, err := schedule.Add([]Reservation{{Token: "booked", StartTime: now.Add(10 * time.Minute), EndTime: now.Add(2 * time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+	token := signToken(t, "key", TokenClaims{StationID: "lab-1", Role: RoleStudent, NotBefore: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	validator := newTestValidator(schedule, func() { t.Error("started ran for a reserved station") })
+
+	if _, ok := validator.Authorize(token); ok {
+		t.Fatal("a signed token took a station reserved for someone else")
+	}
+	if GetCurrentSession().IsActive() {
+		t.Fatal("a session was started")
+	}
+}
@@ -0,0 +1,75 @@
+package currentsession
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Sessions are started, moved, checked and expired from different goroutines
+// at once: HTTP handlers, the schedule, token authorization and timers. Run
+// with -race.
+func TestCurrentSessionConcurrentAccess(t *testing.T) {
+	session := &CurrentSession{}
+	changes := 0
+	var changesMu sync.Mutex
+	session.SetOnChange(func() {
+		// onChange runs unlocked, so it may read the session like the state store does
+		session.State()
+		changesMu.Lock()
+		changes++
+		changesMu.Unlock()
+	})
+
+	end := time.Now().Add(time.Hour)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				switch i % 4 {
+				case 0:
+					session.Set("token", end)
+					session.SetObserverToken("observer")
+				case 1:
+					session.SetEndTime(end.Add(time.Minute))
+					session.SetIfIdle("signed", end)
+				case 2:
+					session.ValidateToken("token")
+					session.ValidateObserverToken("observer")
+					session.HasToken("signed")
+					session.ID()
+				case 3:
+					session.Reset()
+					session.IsActive()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if changes == 0 {
+		t.Fatal("onChange never ran")
+	}
+}
+
+func TestSetIfIdle(t *testing.T) {
+	session := &CurrentSession{}
+	end := time.Now().Add(time.Hour)
+
+	if !session.SetIfIdle("first", end) {
+		t.Fatal("SetIfIdle refused an idle station")
+	}
+	if session.SetIfIdle("second", end) {
+		t.Fatal("SetIfIdle replaced an active session")
+	}
+	if !session.ValidateToken("first") || session.ValidateToken("second") {
+		t.Fatal("the first session should still be the current one")
+	}
+
+	session.Reset()
+	if !session.SetIfIdle("second", end) {
+		t.Fatal("SetIfIdle refused the station after a reset")
+	}
+}
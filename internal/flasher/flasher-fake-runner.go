@@ -0,0 +1,79 @@
+package flasher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Script is the canned behaviour of one tool run
+type Script struct {
+	Lines []Line
+	// LineDelay is waited before each line, to mimic slow progress output
+	LineDelay time.Duration
+	// Hang keeps the tool running after its output until the context is done
+	Hang bool
+	// Err is returned as the tool's exit error
+	Err error
+}
+
+// FakeRunner replays scripted tool output instead of starting processes.
+// Scripts for a command name are used in order and the last one repeats.
+type FakeRunner struct {
+	mu       sync.Mutex
+	scripts  map[string][]Script
+	commands []Command
+}
+
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{scripts: map[string][]Script{}}
+}
+
+// Add queues scripts for the tool with the given name
+func (r *FakeRunner) Add(name string, scripts ...Script) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts[name] = append(r.scripts[name], scripts...)
+}
+
+// Commands returns every command run so far, oldest first
+func (r *FakeRunner) Commands() []Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Command(nil), r.commands...)
+}
+
+func (r *FakeRunner) Run(ctx context.Context, cmd Command, onLine func(Line) bool) error {
+	r.mu.Lock()
+	r.commands = append(r.commands, cmd)
+	queue := r.scripts[cmd.Name]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("failed to start %s: executable file not found", cmd.Name)
+	}
+	script := queue[0]
+	if len(queue) > 1 {
+		r.scripts[cmd.Name] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	for _, line := range script.Lines {
+		if script.LineDelay > 0 {
+			select {
+			case <-time.After(script.LineDelay):
+			case <-ctx.Done():
+				return fmt.Errorf("%s did not finish: %w", cmd.Name, ctx.Err())
+			}
+		}
+		if !onLine(line) {
+			return nil
+		}
+	}
+
+	if script.Hang {
+		<-ctx.Done()
+		return fmt.Errorf("%s did not finish: %w", cmd.Name, ctx.Err())
+	}
+	return script.Err
+}
@@ -0,0 +1,149 @@
+package flasher
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command is one invocation of an external tool
+type Command struct {
+	Name string
+	Args []string
+	// Stdin is written to the tool's standard input, which is closed afterwards
+	Stdin string
+}
+
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Line is one line of tool output
+type Line struct {
+	Text   string
+	Stderr bool
+}
+
+// Runner starts external tools. Run calls onLine for every output line until the
+// tool exits or onLine returns false, in which case the tool is killed and Run
+// returns nil. A non-zero exit status or an expired ctx is returned as an error.
+type Runner interface {
+	Run(ctx context.Context, cmd Command, onLine func(Line) bool) error
+}
+
+// ExecRunner runs tools as child processes
+type ExecRunner struct{}
+
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{}
+}
+
+func (r *ExecRunner) Run(ctx context.Context, command Command, onLine func(Line) bool) error {
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+	// Don't let a grandchild holding the pipes open keep us waiting after a kill
+	cmd.WaitDelay = 2 * time.Second
+	if command.Stdin != "" {
+		cmd.Stdin = strings.NewReader(command.Stdin)
+	}
+	fmt.Println("Running command:", cmd.String())
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", command.Name, err)
+	}
+
+	lines := make(chan Line)
+	var wg sync.WaitGroup
+	scan := func(reader io.Reader, isStderr bool) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines <- Line{Text: scanner.Text(), Stderr: isStderr}
+		}
+	}
+	wg.Add(2)
+	go scan(stdout, false)
+	go scan(stderr, true)
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	// Once the tool is killed, a grandchild may still hold the pipes open, so stop
+	// reading at that point rather than when the pipes reach EOF
+	killed := make(chan struct{})
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-killed:
+		case <-finished:
+			return
+		}
+		stdout.Close()
+		stderr.Close()
+	}()
+
+	stopped := false
+	for line := range lines {
+		if line.Stderr {
+			fmt.Println("stderr:", line.Text)
+		} else {
+			fmt.Println("stdout:", line.Text)
+		}
+		if !stopped && !onLine(line) {
+			stopped = true
+			cmd.Process.Kill()
+			close(killed)
+		}
+	}
+
+	err = cmd.Wait()
+	if stopped {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%s did not finish: %w", command.Name, ctxErr)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("%s exited with status %d", command.Name, exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run command: %w", err)
+	}
+	return nil
+}
+
+// collectOutput returns an onLine callback that keeps the whole output
+func collectOutput(output *strings.Builder, next func(Line) bool) func(Line) bool {
+	return func(line Line) bool {
+		output.WriteString(line.Text)
+		output.WriteString("\n")
+		if next == nil {
+			return true
+		}
+		return next(line)
+	}
+}
+
+// RunCollect runs the command to completion and returns its combined output
+func RunCollect(ctx context.Context, runner Runner, cmd Command, onLine func(Line) bool) (string, error) {
+	var output strings.Builder
+	err := runner.Run(ctx, cmd, collectOutput(&output, onLine))
+	return output.String(), err
+}
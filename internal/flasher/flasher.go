@@ -0,0 +1,23 @@
+package flasher
+
+import "errors"
+
+// ProgressFunc receives the completion percentage reported by a programming tool
+type ProgressFunc func(percent int)
+
+// Flasher programs and controls one target board through an external tool
+type Flasher interface {
+	// Flash writes the firmware image. progress may be nil.
+	Flash(firmwarePath string, progress ProgressFunc) error
+	// Verify checks that the target holds the given firmware image
+	Verify(firmwarePath string) error
+	// Reset restarts the target
+	Reset() error
+	// Probe checks that the programmer can see a target
+	Probe() error
+}
+
+var (
+	ErrBusy         = errors.New("flash is already in progress")
+	ErrNotSupported = errors.New("operation not supported by this flasher")
+)
@@ -1,112 +1,173 @@
 package fpga
 
 import (
-	"bufio"
+	"context"
+	"digitrans-lab-go/internal/config"
+	"digitrans-lab-go/internal/flasher"
 	"fmt"
-	"io"
-	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+const (
+	flashTimeout   = 3 * time.Minute
+	commandTimeout = 30 * time.Second
+)
+
+var (
+	// urjtag's "svf ... progress" prints lines ending in e.g. "45%"
+	svfProgressPattern = regexp.MustCompile(`(\d+)%`)
+	chainLengthPattern = regexp.MustCompile(`Chain length: (\d+)`)
+)
+
+// FPGA programs an FPGA over bit-banged JTAG with urjtag
 type FPGA struct {
-	TDI int
-	TMS int
-	TCK int
-	TDO int
+	runner     flasher.Runner
+	flashMutex sync.Mutex
+
+	mu  sync.Mutex
+	cfg config.FPGAConfig
 }
 
-func CreateFPGA(TDI, TDO, TCK, TMS int) *FPGA {
+func CreateFPGA(runner flasher.Runner, cfg config.FPGAConfig) *FPGA {
 	return &FPGA{
-		TDI: TDI,
-		TMS: TMS,
-		TCK: TCK,
-		TDO: TDO,
+		runner: runner,
+		cfg:    cfg,
 	}
 }
 
-var flashMutex sync.Mutex
+// Configure changes the JTAG pins and tool paths. Operations already running keep the old ones.
+func (fpga *FPGA) Configure(cfg config.FPGAConfig) {
+	fpga.mu.Lock()
+	defer fpga.mu.Unlock()
+	fpga.cfg = cfg
+}
+
+func (fpga *FPGA) settings() config.FPGAConfig {
+	fpga.mu.Lock()
+	defer fpga.mu.Unlock()
+	return fpga.cfg
+}
 
-func (fpga *FPGA) Flash(svfFilePath string) error {
-	if !flashMutex.TryLock() {
+func (fpga *FPGA) Flash(svfFilePath string, progress flasher.ProgressFunc) error {
+	if !fpga.flashMutex.TryLock() {
 		fmt.Println("Failed to lock flash mutex")
-		return fmt.Errorf("Already flashing")
+		return flasher.ErrBusy
 	}
 	fmt.Println("Locked flash mutex")
 	defer func() {
 		fmt.Println("Unlocking flash mutex")
-		flashMutex.Unlock()
+		fpga.flashMutex.Unlock()
 	}()
 
-	return fpga.runUrjtag(svfFilePath)
-}
+	ctx, cancel := context.WithTimeout(context.Background(), flashTimeout)
+	defer cancel()
 
-func (fpga *FPGA) runUrjtag(svfFilePath string) error {
-	// Start the urjtag process
-	cmd := exec.Command("/home/pi/urjtag-2021.03/src/apps/jtag/jtag")
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("Failed to get stdin pipe: %w", err)
-	}
-	stdout, err := cmd.StdoutPipe()
+	var result error
+	done := false
+	err := fpga.runUrjtag(ctx, []string{
+		"detect",
+		"idcode",
+		"include " + fpga.settings().BSDLPath,
+		"svf " + svfFilePath + " progress",
+	}, func(line flasher.Line) bool {
+		if line.Stderr {
+			if strings.HasPrefix(line.Text, "warning:") {
+				return true
+			}
+			result, done = fmt.Errorf("urjtag stderr: %s", line.Text), true
+			return false
+		}
+		if strings.Contains(line.Text, "Scanned device output matched expected TDO values") {
+			done = true
+			return false
+		}
+		if progress != nil && strings.Contains(line.Text, "SVF") {
+			if m := svfProgressPattern.FindStringSubmatch(line.Text); m != nil {
+				percent, _ := strconv.Atoi(m[1])
+				progress(percent)
+			}
+		}
+		return true
+	})
 	if err != nil {
-		return fmt.Errorf("Failed to get stdout pipe: %w", err)
+		return err
 	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("Failed to get stderr pipe: %w", err)
+	if !done {
+		return fmt.Errorf("urjtag exited without confirming the SVF was applied")
 	}
+	return result
+}
 
-	err = cmd.Start()
-	if err != nil {
-		return fmt.Errorf("Failed to start urjtag: %w", err)
-	}
+// Verify is not supported: the SVF player already compares every TDO value
+// while flashing and the FPGA's SRAM configuration cannot be read back.
+func (fpga *FPGA) Verify(svfFilePath string) error {
+	return flasher.ErrNotSupported
+}
 
-	resultChan := make(chan error, 1)
+// Reset puts the JTAG TAP controller back into Test-Logic-Reset
+func (fpga *FPGA) Reset() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
 
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			line := scanner.Text()
-			fmt.Println("stdout:", line)
-			if strings.Contains(line, "Scanned device output matched expected TDO values") {
-				resultChan <- nil
-				return
-			}
+	var result error
+	err := fpga.runUrjtag(ctx, []string{"detect", "reset"}, func(line flasher.Line) bool {
+		if line.Stderr && !strings.HasPrefix(line.Text, "warning:") {
+			result = fmt.Errorf("urjtag stderr: %s", line.Text)
+			return false
 		}
-	}()
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
 
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			line := scanner.Text()
-			fmt.Println("stderr:", line)
-			if !strings.HasPrefix(line, "warning:") {
-				resultChan <- fmt.Errorf("urjtag stderr: %s", line)
-				return
-			}
+// Probe checks that the JTAG chain has at least one device on it
+func (fpga *FPGA) Probe() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var result error
+	chainLength := 0
+	err := fpga.runUrjtag(ctx, []string{"detect"}, func(line flasher.Line) bool {
+		if line.Stderr && !strings.HasPrefix(line.Text, "warning:") {
+			result = fmt.Errorf("urjtag stderr: %s", line.Text)
+			return false
 		}
-	}()
-
-	// Send commands to urjtag
-	commands := []string{
-		fmt.Sprintf("cable gpio tdi=%d tdo=%d tck=%d tms=%d", fpga.TDI, fpga.TDO, fpga.TCK, fpga.TMS),
-		"detect",
-		"idcode",
-		"include /home/pi/EP4CE10E22.bsdl",
-		"svf " + svfFilePath + " progress",
-		"quit",
+		if m := chainLengthPattern.FindStringSubmatch(line.Text); m != nil {
+			chainLength, _ = strconv.Atoi(m[1])
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		return result
+	}
+	if chainLength == 0 {
+		return fmt.Errorf("no device found on the JTAG chain")
 	}
+	return nil
+}
 
-	for _, cmd := range commands {
+// runUrjtag feeds the commands to urjtag after configuring the GPIO cable
+func (fpga *FPGA) runUrjtag(ctx context.Context, commands []string, onLine func(flasher.Line) bool) error {
+	cfg := fpga.settings()
+	script := []string{fmt.Sprintf("cable gpio tdi=%d tdo=%d tck=%d tms=%d", cfg.TDI, cfg.TDO, cfg.TCK, cfg.TMS)}
+	script = append(script, commands...)
+	script = append(script, "quit")
+	for _, cmd := range script {
 		fmt.Println("Sending command:", cmd)
-		io.WriteString(stdin, cmd+"\n")
 	}
 
-	result := <-resultChan
-
-	cmd.Process.Kill()
-	cmd.Wait()
-
-	return result
-}
\ No newline at end of file
+	return fpga.runner.Run(ctx, flasher.Command{
+		Name:  cfg.UrjtagPath,
+		Stdin: strings.Join(script, "\n") + "\n",
+	}, onLine)
+}
@@ -3,28 +3,207 @@ package gpio
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"strconv"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Linux GPIO character device uAPI (v2), see include/uapi/linux/gpio.h
+const (
+	gpioV2LinesMax        = 64
+	gpioMaxNameSize       = 32
+	gpioV2LineNumAttrsMax = 10
+	gpioV2LineFlagOutput  = 1 << 3
+	// gpioV2LineAttrIDOutputValues sets the level output lines start at
+	gpioV2LineAttrIDOutputValues = 2
+)
+
+type gpioV2LineAttribute struct {
+	ID      uint32
+	Padding uint32
+	Value   uint64
+}
+
+type gpioV2LineConfigAttribute struct {
+	Attr gpioV2LineAttribute
+	Mask uint64
+}
+
+type gpioV2LineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	Padding  [5]uint32
+	Attrs    [gpioV2LineNumAttrsMax]gpioV2LineConfigAttribute
+}
+
+type gpioV2LineRequest struct {
+	Offsets         [gpioV2LinesMax]uint32
+	Consumer        [gpioMaxNameSize]byte
+	Config          gpioV2LineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	Padding         [5]uint32
+	Fd              int32
+}
+
+type gpioV2LineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+func iowr(nr uintptr, size uintptr) uintptr {
+	return (3 << 30) | (size << 16) | (0xB4 << 8) | nr
+}
+
+var (
+	gpioV2GetLineIoctl       = iowr(0x07, unsafe.Sizeof(gpioV2LineRequest{}))
+	gpioV2LineGetValuesIoctl = iowr(0x0E, unsafe.Sizeof(gpioV2LineValues{}))
+	gpioV2LineSetValuesIoctl = iowr(0x0F, unsafe.Sizeof(gpioV2LineValues{}))
 )
 
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// cdevChip drives lines through /dev/gpiochipN, so a write is a single ioctl
+// instead of a pinctrl process
+type cdevChip struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+func OpenChip(path string) (Chip, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gpio chip %s: %w", path, err)
+	}
+	return &cdevChip{file: file}, nil
+}
+
+func (c *cdevChip) Pin(consumer string, offset int, initial int) (Pin, error) {
+	group, err := c.group(consumer, []int{offset}, []int{initial})
+	if err != nil {
+		return nil, err
+	}
+	return &groupPin{group: group}, nil
+}
+
+func (c *cdevChip) Group(consumer string, offsets ...int) (Group, error) {
+	return c.group(consumer, offsets, make([]int, len(offsets)))
+}
+
+func (c *cdevChip) group(consumer string, offsets []int, initial []int) (Group, error) {
+	if err := validateOffsets(offsets); err != nil {
+		return nil, err
+	}
+
+	req := gpioV2LineRequest{NumLines: uint32(len(offsets))}
+	for i, offset := range offsets {
+		req.Offsets[i] = uint32(offset)
+	}
+	copy(req.Consumer[:gpioMaxNameSize-1], consumer)
+	req.Config.Flags = gpioV2LineFlagOutput
 
-func WritePin(pinNumber int, value int) error {
-	if value != 0 {
-		if err := runCommand("pinctrl", "set", strconv.Itoa(pinNumber), "op", "dh"); err != nil {
-			return fmt.Errorf("failed to set up boot0 pin: %w", err)
+	// Without this attribute the kernel drives the lines low as soon as they are requested
+	values := gpioV2LineConfigAttribute{Attr: gpioV2LineAttribute{ID: gpioV2LineAttrIDOutputValues}}
+	for i, value := range initial {
+		values.Mask |= 1 << i
+		if value != 0 {
+			values.Attr.Value |= 1 << i
 		}
-		return nil
 	}
+	req.Config.Attrs[0] = values
+	req.Config.NumAttrs = 1
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if err := runCommand("pinctrl", "set", strconv.Itoa(pinNumber), "op", "dl"); err != nil {
-		return fmt.Errorf("failed to set up boot0 pin: %w", err)
+	if err := ioctl(c.file.Fd(), gpioV2GetLineIoctl, unsafe.Pointer(&req)); err != nil {
+		return nil, fmt.Errorf("failed to request gpio lines %v: %w", offsets, err)
+	}
+
+	return &cdevGroup{
+		fd:      int(req.Fd),
+		offsets: append([]int(nil), offsets...),
+	}, nil
+}
+
+func (c *cdevChip) Close() error {
+	return c.file.Close()
+}
+
+type cdevGroup struct {
+	fd      int
+	offsets []int
+	mu      sync.Mutex
+}
+
+func (g *cdevGroup) Offsets() []int {
+	return append([]int(nil), g.offsets...)
+}
+
+func (g *cdevGroup) Write(values ...int) error {
+	if err := validateValues(g.offsets, values); err != nil {
+		return err
+	}
+
+	// Bits and mask are indexed by position in the request, not by line offset
+	lv := gpioV2LineValues{}
+	for i, value := range values {
+		lv.Mask |= 1 << i
+		if value != 0 {
+			lv.Bits |= 1 << i
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.fd < 0 {
+		return fmt.Errorf("gpio lines %v are closed", g.offsets)
+	}
+	if err := ioctl(uintptr(g.fd), gpioV2LineSetValuesIoctl, unsafe.Pointer(&lv)); err != nil {
+		return fmt.Errorf("failed to write gpio lines %v: %w", g.offsets, err)
 	}
 	return nil
 }
 
-func runCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
\ No newline at end of file
+func (g *cdevGroup) Read() ([]int, error) {
+	lv := gpioV2LineValues{}
+	for i := range g.offsets {
+		lv.Mask |= 1 << i
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.fd < 0 {
+		return nil, fmt.Errorf("gpio lines %v are closed", g.offsets)
+	}
+	if err := ioctl(uintptr(g.fd), gpioV2LineGetValuesIoctl, unsafe.Pointer(&lv)); err != nil {
+		return nil, fmt.Errorf("failed to read gpio lines %v: %w", g.offsets, err)
+	}
+
+	values := make([]int, len(g.offsets))
+	for i := range values {
+		values[i] = int((lv.Bits >> i) & 1)
+	}
+	return values, nil
+}
+
+func (g *cdevGroup) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.fd < 0 {
+		return nil
+	}
+	err := unix.Close(g.fd)
+	g.fd = -1
+	return err
+}
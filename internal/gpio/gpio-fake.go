@@ -0,0 +1,161 @@
+package gpio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FakeWrite is one recorded write to a group of lines
+type FakeWrite struct {
+	Consumer string
+	Offsets  []int
+	Values   []int
+	Time     time.Time
+}
+
+// FakeChip is an in-memory Chip that records every write, for tests and for
+// running the backend on machines without GPIO
+type FakeChip struct {
+	mu        sync.Mutex
+	levels    map[int]int
+	requested map[int]string
+	writes    []FakeWrite
+}
+
+func NewFakeChip() *FakeChip {
+	return &FakeChip{
+		levels:    map[int]int{},
+		requested: map[int]string{},
+	}
+}
+
+func (c *FakeChip) Pin(consumer string, offset int, initial int) (Pin, error) {
+	group, err := c.group(consumer, []int{offset}, []int{initial})
+	if err != nil {
+		return nil, err
+	}
+	return &groupPin{group: group}, nil
+}
+
+func (c *FakeChip) Group(consumer string, offsets ...int) (Group, error) {
+	return c.group(consumer, offsets, make([]int, len(offsets)))
+}
+
+func (c *FakeChip) group(consumer string, offsets []int, initial []int) (Group, error) {
+	if err := validateOffsets(offsets); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, offset := range offsets {
+		if owner, busy := c.requested[offset]; busy {
+			return nil, fmt.Errorf("gpio line %d is already requested by %s", offset, owner)
+		}
+	}
+	for i, offset := range offsets {
+		c.requested[offset] = consumer
+		c.levels[offset] = min(initial[i], 1)
+	}
+
+	return &fakeGroup{
+		chip:     c,
+		consumer: consumer,
+		offsets:  append([]int(nil), offsets...),
+	}, nil
+}
+
+func (c *FakeChip) Close() error {
+	return nil
+}
+
+// Level returns the level a line is driven to
+func (c *FakeChip) Level(offset int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.levels[offset]
+}
+
+// Writes returns every write made through the chip, oldest first
+func (c *FakeChip) Writes() []FakeWrite {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]FakeWrite(nil), c.writes...)
+}
+
+// ResetWrites clears the recorded write history
+func (c *FakeChip) ResetWrites() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes = nil
+}
+
+type fakeGroup struct {
+	chip     *FakeChip
+	consumer string
+	offsets  []int
+	closed   bool
+}
+
+func (g *fakeGroup) Offsets() []int {
+	return append([]int(nil), g.offsets...)
+}
+
+func (g *fakeGroup) Write(values ...int) error {
+	if err := validateValues(g.offsets, values); err != nil {
+		return err
+	}
+
+	g.chip.mu.Lock()
+	defer g.chip.mu.Unlock()
+
+	if g.closed {
+		return fmt.Errorf("gpio lines %v are closed", g.offsets)
+	}
+
+	normalized := make([]int, len(values))
+	for i, value := range values {
+		if value != 0 {
+			normalized[i] = 1
+		}
+		g.chip.levels[g.offsets[i]] = normalized[i]
+	}
+	g.chip.writes = append(g.chip.writes, FakeWrite{
+		Consumer: g.consumer,
+		Offsets:  append([]int(nil), g.offsets...),
+		Values:   normalized,
+		Time:     time.Now(),
+	})
+	return nil
+}
+
+func (g *fakeGroup) Read() ([]int, error) {
+	g.chip.mu.Lock()
+	defer g.chip.mu.Unlock()
+
+	if g.closed {
+		return nil, fmt.Errorf("gpio lines %v are closed", g.offsets)
+	}
+
+	values := make([]int, len(g.offsets))
+	for i, offset := range g.offsets {
+		values[i] = g.chip.levels[offset]
+	}
+	return values, nil
+}
+
+func (g *fakeGroup) Close() error {
+	g.chip.mu.Lock()
+	defer g.chip.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	for _, offset := range g.offsets {
+		delete(g.chip.requested, offset)
+	}
+	return nil
+}
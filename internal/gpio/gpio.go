@@ -0,0 +1,100 @@
+package gpio
+
+import "fmt"
+
+// Chip hands out output lines of one GPIO controller
+type Chip interface {
+	// Pin requests a single output line, driven to initial (0 or 1) from the
+	// moment it is requested, so it doesn't glitch low first
+	Pin(consumer string, offset int, initial int) (Pin, error)
+	// Group requests several output lines in one request, so they are always written together
+	Group(consumer string, offsets ...int) (Group, error)
+	Close() error
+}
+
+// Pin is a single requested output line
+type Pin interface {
+	Offset() int
+	Write(value int) error
+	// Read returns the level the line is currently driven to
+	Read() (int, error)
+	Close() error
+}
+
+// Group is a set of output lines that are written atomically.
+// Values are passed and returned in the order the offsets were requested.
+type Group interface {
+	Offsets() []int
+	Write(values ...int) error
+	Read() ([]int, error)
+	Close() error
+}
+
+const (
+	BackendCdev = "cdev"
+	BackendFake = "fake"
+)
+
+// Open returns the chip for the given backend. The path is only used by the character device backend.
+func Open(backend string, path string) (Chip, error) {
+	switch backend {
+	case "", BackendCdev:
+		return OpenChip(path)
+	case BackendFake:
+		return NewFakeChip(), nil
+	default:
+		return nil, fmt.Errorf("unknown gpio backend: %s", backend)
+	}
+}
+
+// groupPin adapts a one-line Group to the Pin interface
+type groupPin struct {
+	group Group
+}
+
+func (p *groupPin) Offset() int {
+	return p.group.Offsets()[0]
+}
+
+func (p *groupPin) Write(value int) error {
+	return p.group.Write(value)
+}
+
+func (p *groupPin) Read() (int, error) {
+	values, err := p.group.Read()
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+func (p *groupPin) Close() error {
+	return p.group.Close()
+}
+
+func validateOffsets(offsets []int) error {
+	if len(offsets) == 0 {
+		return fmt.Errorf("no gpio lines requested")
+	}
+	if len(offsets) > 64 {
+		return fmt.Errorf("too many gpio lines requested: %d", len(offsets))
+	}
+	seen := map[int]bool{}
+	for _, offset := range offsets {
+		if offset < 0 {
+			return fmt.Errorf("invalid gpio line: %d", offset)
+		}
+		if seen[offset] {
+			return fmt.Errorf("gpio line %d requested twice", offset)
+		}
+		seen[offset] = true
+	}
+	return nil
+}
+
+func validateValues(offsets []int, values []int) error {
+	if len(values) != len(offsets) {
+		return fmt.Errorf("expected %d gpio values, got %d", len(offsets), len(values))
+	}
+	return nil
+}
@@ -6,36 +6,32 @@ import (
 )
 
 type driverMultiplexer struct {
-	a1PinNumber int
-	a2PinNumber int
-
-	a1Val int
-	a2Val int
+	// A1 and A2 address lines, requested together so a channel switch is a single write
+	lines gpio.Group
 }
 
-func newDriverMultiplexer(a1PinNumber int, a2PinNumber int) *driverMultiplexer {
-	driver := &driverMultiplexer{
-		a1PinNumber: a1PinNumber,
-		a2PinNumber: a2PinNumber,
+func newDriverMultiplexer(chip gpio.Chip, a1PinNumber int, a2PinNumber int) (*driverMultiplexer, error) {
+	lines, err := chip.Group("multiplexer", a1PinNumber, a2PinNumber)
+	if err != nil {
+		return nil, err
 	}
 
-	driver.selectInputChannel(1)
+	driver := &driverMultiplexer{
+		lines: lines,
+	}
 
-	return driver
-}
+	if err := driver.selectInputChannel(1); err != nil {
+		return nil, err
+	}
 
-func (d *driverMultiplexer) setA1(val int) {
-	d.a1Val = val
-	gpio.WritePin(d.a1PinNumber, val)
+	return driver, nil
 }
 
-func (d *driverMultiplexer) setA2(val int) {
-	d.a2Val = val
-	gpio.WritePin(d.a2PinNumber, val)
-}
+// Two select lines pick one of four inputs
+const channelCount = 4
 
 func (d *driverMultiplexer) selectInputChannel(channel int) error {
-	if channel < 1 || channel > 4 {
+	if channel < 1 || channel > channelCount {
 		return fmt.Errorf("invalid channel: %d", channel)
 	}
 
@@ -46,27 +42,30 @@ func (d *driverMultiplexer) selectInputChannel(channel int) error {
 
 	fmt.Printf("setting a1Val: %d, a2Val: %d\n", a1Val, a2Val)
 
-	d.setA1(a1Val)
-	d.setA2(a2Val)
-
-	return nil
+	return d.lines.Write(a1Val, a2Val)
 }
 
 func (d *driverMultiplexer) getInputChannel() (int, error) {
-	fmt.Printf("getting a1Val: %d, a2Val: %d\n", d.a1Val, d.a2Val)
+	values, err := d.lines.Read()
+	if err != nil {
+		return 0, err
+	}
+	a1Val, a2Val := values[0], values[1]
+
+	fmt.Printf("getting a1Val: %d, a2Val: %d\n", a1Val, a2Val)
 
-	if d.a2Val == 0 && d.a1Val == 0 {
+	if a2Val == 0 && a1Val == 0 {
 		return 1, nil
 	}
-	if d.a2Val == 0 && d.a1Val == 1 {
+	if a2Val == 0 && a1Val == 1 {
 		return 2, nil
 	}
-	if d.a2Val == 1 && d.a1Val == 0 {
+	if a2Val == 1 && a1Val == 0 {
 		return 3, nil
 	}
-	if d.a2Val == 1 && d.a1Val == 1 {
+	if a2Val == 1 && a1Val == 1 {
 		return 4, nil
 	}
 
-	return 0, fmt.Errorf("invalid channel: %d", d.a1Val, d.a2Val)
+	return 0, fmt.Errorf("invalid channel: a1=%d a2=%d", a1Val, a2Val)
 }
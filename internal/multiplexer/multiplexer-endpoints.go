@@ -6,20 +6,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+type SelectInputChannelRequest struct {
+	Multiplexer int `json:"multiplexer" binding:"required"`
+	Channel     int `json:"channel" binding:"required"`
+}
 
 func HandleSelectInputChannel(device *MultiplexerModule) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		var request struct {
-			Multiplexer int `json:"multiplexer" binding:"required"`
-			Channel     int `json:"channel" binding:"required"`
-		}
+		var request SelectInputChannelRequest
 
 		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		err := device.selectInputChannel(request.Multiplexer, request.Channel)
+		err := device.SelectInputChannel(request.Multiplexer, request.Channel)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -31,12 +32,12 @@ func HandleSelectInputChannel(device *MultiplexerModule) func(c *gin.Context) {
 
 func HandleGetInputChannel(device *MultiplexerModule) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		channel1, err := device.getInputChannel(1)
+		channel1, err := device.InputChannel(1)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		channel2, err := device.getInputChannel(2)	
+		channel2, err := device.InputChannel(2)	
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
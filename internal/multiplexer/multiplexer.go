@@ -1,20 +1,53 @@
 package multiplexer
 
-import "fmt"
+import (
+	"digitrans-lab-go/internal/gpio"
+	"errors"
+	"fmt"
+)
 
 type MultiplexerModule struct {
 	mux1 *driverMultiplexer
 	mux2 *driverMultiplexer
 }
 
-func NewMultiplexerModule(A0_1 int, A0_2 int, A1_1 int, A1_2 int) *MultiplexerModule {
-	return &MultiplexerModule{
-		mux1: newDriverMultiplexer(A0_1, A0_2),
-		mux2: newDriverMultiplexer(A1_1, A1_2),
+func NewMultiplexerModule(chip gpio.Chip, A0_1 int, A0_2 int, A1_1 int, A1_2 int) (*MultiplexerModule, error) {
+	mux1, err := newDriverMultiplexer(chip, A0_1, A0_2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up multiplexer 1: %w", err)
+	}
+	mux2, err := newDriverMultiplexer(chip, A1_1, A1_2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up multiplexer 2: %w", err)
 	}
+	return &MultiplexerModule{
+		mux1: mux1,
+		mux2: mux2,
+	}, nil
+}
+
+// Close releases the GPIO lines of both multiplexers
+func (m *MultiplexerModule) Close() error {
+	return errors.Join(m.mux1.lines.Close(), m.mux2.lines.Close())
+}
+
+// Count is the number of multiplexers on the module
+func (m *MultiplexerModule) Count() int {
+	return 2
+}
+
+// ChannelCount is the number of inputs of each multiplexer
+func (m *MultiplexerModule) ChannelCount() int {
+	return channelCount
+}
+
+// Reset selects channel 1 on both multiplexers, as after power-up
+func (m *MultiplexerModule) Reset() error {
+	return errors.Join(m.mux1.selectInputChannel(1), m.mux2.selectInputChannel(1))
 }
 
-func (m *MultiplexerModule) selectInputChannel(mux int, channel int) error {
+// SelectInputChannel connects input channel of multiplexer mux, both counted from 1
+func (m *MultiplexerModule) SelectInputChannel(mux int, channel int) error {
 	if mux == 1 {
 		return m.mux1.selectInputChannel(channel)
 	} else if mux == 2 {
@@ -23,7 +56,8 @@ func (m *MultiplexerModule) selectInputChannel(mux int, channel int) error {
 	return fmt.Errorf("invalid multiplexer: %d", mux)
 }
 
-func (m *MultiplexerModule) getInputChannel(mux int) (int, error) {
+// InputChannel is the input selected on multiplexer mux
+func (m *MultiplexerModule) InputChannel(mux int) (int, error) {
 	if mux == 1 {
 		return m.mux1.getInputChannel()
 	} else if mux == 2 {
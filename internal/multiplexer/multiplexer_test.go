@@ -0,0 +1,105 @@
+package multiplexer
+
+import (
+	"digitrans-lab-go/internal/gpio"
+	"slices"
+	"testing"
+)
+
+func newTestModule(t *testing.T) (*MultiplexerModule, *gpio.FakeChip) {
+	t.Helper()
+	chip := gpio.NewFakeChip()
+	module, err := NewMultiplexerModule(chip, 5, 6, 13, 19)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return module, chip
+}
+
+func TestNewMultiplexerModuleSelectsFirstChannel(t *testing.T) {
+	_, chip := newTestModule(t)
+
+	writes := chip.Writes()
+	if len(writes) != 2 {
+		t.Fatalf("got %d writes, want one per multiplexer", len(writes))
+	}
+	for i, offsets := range [][]int{{5, 6}, {13, 19}} {
+		if !slices.Equal(writes[i].Offsets, offsets) || !slices.Equal(writes[i].Values, []int{0, 0}) {
+			t.Errorf("write %d = %v %v, want %v [0 0]", i, writes[i].Offsets, writes[i].Values, offsets)
+		}
+	}
+}
+
+// A channel switch sets both address lines in a single write, so the
+// multiplexer never passes through another channel
+func TestSelectInputChannelWritesAddressLines(t *testing.T) {
+	module, chip := newTestModule(t)
+
+	for channel, values := range map[int][]int{1: {0, 0}, 2: {1, 0}, 3: {0, 1}, 4: {1, 1}} {
+		chip.ResetWrites()
+		if err := module.SelectInputChannel(2, channel); err != nil {
+			t.Fatal(err)
+		}
+
+		writes := chip.Writes()
+		if len(writes) != 1 {
+			t.Fatalf("channel %d: got %d writes, want 1", channel, len(writes))
+		}
+		if writes[0].Consumer != "multiplexer" || !slices.Equal(writes[0].Offsets, []int{13, 19}) || !slices.Equal(writes[0].Values, values) {
+			t.Errorf("channel %d: wrote %v to %v as %q, want %v to [13 19]", channel, writes[0].Values, writes[0].Offsets, writes[0].Consumer, values)
+		}
+		if got, err := module.InputChannel(2); err != nil || got != channel {
+			t.Errorf("InputChannel(2) = %d, %v, want %d", got, err, channel)
+		}
+	}
+	if got, _ := module.InputChannel(1); got != 1 {
+		t.Errorf("multiplexer 1 moved to channel %d", got)
+	}
+}
+
+func TestSelectInputChannelRejectsInvalidInput(t *testing.T) {
+	module, chip := newTestModule(t)
+	chip.ResetWrites()
+
+	for _, request := range [][2]int{{0, 1}, {3, 1}, {1, 0}, {1, 5}} {
+		if err := module.SelectInputChannel(request[0], request[1]); err == nil {
+			t.Errorf("SelectInputChannel(%d, %d) succeeded", request[0], request[1])
+		}
+	}
+	if writes := chip.Writes(); len(writes) != 0 {
+		t.Fatalf("invalid requests wrote %v", writes)
+	}
+}
+
+func TestResetSelectsFirstChannel(t *testing.T) {
+	module, _ := newTestModule(t)
+	if err := module.SelectInputChannel(1, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := module.SelectInputChannel(2, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := module.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	for mux := 1; mux <= module.Count(); mux++ {
+		if got, _ := module.InputChannel(mux); got != 1 {
+			t.Errorf("multiplexer %d is on channel %d after a reset", mux, got)
+		}
+	}
+}
+
+func TestCloseReleasesLines(t *testing.T) {
+	module, chip := newTestModule(t)
+	if _, err := NewMultiplexerModule(chip, 5, 6, 13, 19); err == nil {
+		t.Fatal("the lines were requested twice")
+	}
+
+	if err := module.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMultiplexerModule(chip, 5, 6, 13, 19); err != nil {
+		t.Fatalf("reopening after Close: %v", err)
+	}
+}
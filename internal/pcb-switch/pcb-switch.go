@@ -7,28 +7,52 @@ import (
 )
 
 type PCBSwitch struct {
-	pin int
+	pin gpio.Pin
 }
 
-func NewPCBSwitch(pin int) *PCBSwitch {
-	return &PCBSwitch{
-		pin: pin,
+// NewPCBSwitch takes over the power pin, leaving the board powered on or off as
+// asked without switching it in between
+func NewPCBSwitch(chip gpio.Chip, pin int, on bool) (*PCBSwitch, error) {
+	initial := 0
+	if on {
+		initial = 1
+	}
+	p, err := chip.Pin("pcb-switch", pin, initial)
+	if err != nil {
+		return nil, err
 	}
+	return &PCBSwitch{
+		pin: p,
+	}, nil
+}
+
+// Close releases the GPIO line
+func (s *PCBSwitch) Close() error {
+	return s.pin.Close()
 }
 
 func (s *PCBSwitch) PowerOn() error {
-	return gpio.WritePin(s.pin, 1)
+	return s.pin.Write(1)
 }
 
 func (s *PCBSwitch) PowerOff() error {
-	return gpio.WritePin(s.pin, 0)
+	return s.pin.Write(0)
 }
 
-func (s *PCBSwitch) Reset() error {
+// IsPoweredOn reads back the level of the power pin
+func (s *PCBSwitch) IsPoweredOn() (bool, error) {
+	value, err := s.pin.Read()
+	if err != nil {
+		return false, err
+	}
+	return value == 1, nil
+}
 
-	s.PowerOff()
+// Reset power-cycles the board
+func (s *PCBSwitch) Reset() error {
+	if err := s.PowerOff(); err != nil {
+		return err
+	}
 	time.Sleep(1 * time.Second)
-	s.PowerOn()
-
-	return nil
-}
\ No newline at end of file
+	return s.PowerOn()
+}
@@ -0,0 +1,93 @@
+package pcbswitch
+
+import (
+	"digitrans-lab-go/internal/gpio"
+	"slices"
+	"testing"
+	"time"
+)
+
+const powerPin = 26
+
+// Taking over the pin mustn't glitch the board, e.g. power-cycle a board whose
+// session survived a restart
+func TestNewPCBSwitchKeepsRequestedLevel(t *testing.T) {
+	for _, on := range []bool{false, true} {
+		chip := gpio.NewFakeChip()
+		s, err := NewPCBSwitch(chip, powerPin, on)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if writes := chip.Writes(); len(writes) != 0 {
+			t.Errorf("on=%v: opening wrote %v", on, writes)
+		}
+		if powered, err := s.IsPoweredOn(); err != nil || powered != on {
+			t.Errorf("on=%v: IsPoweredOn = %v, %v", on, powered, err)
+		}
+	}
+}
+
+func TestPowerOnAndOff(t *testing.T) {
+	chip := gpio.NewFakeChip()
+	s, err := NewPCBSwitch(chip, powerPin, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.PowerOn(); err != nil {
+		t.Fatal(err)
+	}
+	if chip.Level(powerPin) != 1 {
+		t.Fatal("PowerOn left the pin low")
+	}
+	if err := s.PowerOff(); err != nil {
+		t.Fatal(err)
+	}
+	if chip.Level(powerPin) != 0 {
+		t.Fatal("PowerOff left the pin high")
+	}
+
+	writes := chip.Writes()
+	if len(writes) != 2 || writes[0].Consumer != "pcb-switch" || !slices.Equal(writes[0].Offsets, []int{powerPin}) {
+		t.Fatalf("writes = %v", writes)
+	}
+}
+
+func TestResetPowerCycles(t *testing.T) {
+	chip := gpio.NewFakeChip()
+	s, err := NewPCBSwitch(chip, powerPin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := chip.Writes()
+	if len(writes) != 2 || writes[0].Values[0] != 0 || writes[1].Values[0] != 1 {
+		t.Fatalf("writes = %v, want the pin driven low and then high", writes)
+	}
+	if off := writes[1].Time.Sub(writes[0].Time); off < 900*time.Millisecond {
+		t.Errorf("the board was off for only %s", off)
+	}
+}
+
+func TestClosedSwitchFails(t *testing.T) {
+	chip := gpio.NewFakeChip()
+	s, err := NewPCBSwitch(chip, powerPin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.PowerOff(); err == nil {
+		t.Fatal("PowerOff succeeded on a closed switch")
+	}
+	if _, err := NewPCBSwitch(chip, powerPin, false); err != nil {
+		t.Fatalf("the pin wasn't released: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package peripherals
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handle serves a route with the handler built for the current peripheral, or
+// responds with 503 while the peripheral is unavailable
+func Handle[T any](p *Peripheral[T], handler func(T) func(c *gin.Context)) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		value, ok := p.Get()
+		if !ok {
+			AbortUnavailable(c, p.Status())
+			return
+		}
+		handler(value)(c)
+	}
+}
+
+// Require is a middleware that responds with 503 unless all entries are available
+func Require(entries ...Entry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, entry := range entries {
+			if status := entry.Status(); !status.Available {
+				AbortUnavailable(c, status)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// AbortUnavailable writes the response shared by all routes of missing peripherals
+func AbortUnavailable(c *gin.Context, status Status) {
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":      status.Name + " is unavailable",
+		"peripheral": status.Name,
+		"reason":     status.Error,
+	})
+}
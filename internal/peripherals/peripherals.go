@@ -0,0 +1,286 @@
+package peripherals
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Status describes whether a peripheral can currently be used
+type Status struct {
+	Name      string    `json:"name"`
+	Available bool      `json:"available"`
+	Error     string    `json:"error,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// Entry is the type-independent view of a Peripheral used by the Registry
+type Entry interface {
+	Name() string
+	Status() Status
+	Open() bool
+	Reopen() bool
+	Probe() bool
+	watch(fn func(Status))
+}
+
+// Peripheral is an instrument that may be missing at startup and show up later.
+// Until open succeeds the peripheral is unavailable and Get reports false.
+type Peripheral[T any] struct {
+	name  string
+	open  func() (T, error)
+	close func(T) error
+	probe func(T) error
+
+	opening   sync.Mutex
+	mu        sync.RWMutex
+	value     T
+	available bool
+	err       error
+	since     time.Time
+	// changed runs when the peripheral became available or unavailable
+	changed func(Status)
+}
+
+func New[T any](name string, open func() (T, error)) *Peripheral[T] {
+	return &Peripheral[T]{
+		name:  name,
+		open:  open,
+		err:   errors.New("not initialised yet"),
+		since: time.Now(),
+	}
+}
+
+// WithClose sets how the peripheral is released before it is reopened
+func (p *Peripheral[T]) WithClose(close func(T) error) *Peripheral[T] {
+	p.close = close
+	return p
+}
+
+// WithProbe sets how an available peripheral is checked for still being there,
+// e.g. by reading a register. A failed probe makes it unavailable until it
+// opens again.
+func (p *Peripheral[T]) WithProbe(probe func(T) error) *Peripheral[T] {
+	p.probe = probe
+	return p
+}
+
+func (p *Peripheral[T]) Name() string {
+	return p.name
+}
+
+// Get returns the peripheral and whether it is available
+func (p *Peripheral[T]) Get() (T, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.value, p.available
+}
+
+func (p *Peripheral[T]) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	status := Status{Name: p.name, Available: p.available, Since: p.since}
+	if p.err != nil {
+		status.Error = p.err.Error()
+	}
+	return status
+}
+
+// Open initialises the peripheral unless it is already available and reports
+// whether it is available afterwards
+func (p *Peripheral[T]) Open() bool {
+	p.opening.Lock()
+	defer p.opening.Unlock()
+
+	if _, ok := p.Get(); ok {
+		return true
+	}
+	if !p.tryOpen() {
+		return false
+	}
+	p.notify()
+	return true
+}
+
+// Reopen releases the peripheral, if it is open, and opens it again with the
+// current settings. Routes answer 503 in between.
+func (p *Peripheral[T]) Reopen() bool {
+	p.opening.Lock()
+	defer p.opening.Unlock()
+
+	p.mu.Lock()
+	value, wasAvailable := p.value, p.available
+	var zero T
+	p.value = zero
+	p.available = false
+	p.err = errors.New("reopening")
+	p.since = time.Now()
+	p.mu.Unlock()
+
+	if wasAvailable && p.close != nil {
+		if err := p.close(value); err != nil {
+			log.Printf("Error closing %s: %v", p.name, err)
+		}
+	}
+	available := p.tryOpen()
+	if available != wasAvailable {
+		p.notify()
+	}
+	return available
+}
+
+// Probe checks an available peripheral and reports whether it is still
+// available. A peripheral without a probe is taken as it is.
+func (p *Peripheral[T]) Probe() bool {
+	p.opening.Lock()
+	defer p.opening.Unlock()
+
+	value, ok := p.Get()
+	if !ok || p.probe == nil {
+		return ok
+	}
+	err := p.probe(value)
+	if err == nil {
+		return true
+	}
+
+	log.Printf("%s stopped answering: %v", p.name, err)
+	p.mu.Lock()
+	var zero T
+	p.value = zero
+	p.available = false
+	p.err = err
+	p.since = time.Now()
+	p.mu.Unlock()
+
+	if p.close != nil {
+		if err := p.close(value); err != nil {
+			log.Printf("Error closing %s: %v", p.name, err)
+		}
+	}
+	p.notify()
+	return false
+}
+
+func (p *Peripheral[T]) watch(fn func(Status)) {
+	p.opening.Lock()
+	defer p.opening.Unlock()
+	p.changed = fn
+}
+
+// notify must be called with p.opening held
+func (p *Peripheral[T]) notify() {
+	if p.changed != nil {
+		p.changed(p.Status())
+	}
+}
+
+// tryOpen must be called with p.opening held
+func (p *Peripheral[T]) tryOpen() bool {
+	value, err := p.open()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		// Only log when the reason changes so background retries don't flood the log
+		if p.err == nil || p.err.Error() != err.Error() {
+			log.Printf("%s is unavailable: %v", p.name, err)
+		}
+		p.err = err
+		return false
+	}
+	p.value = value
+	p.available = true
+	p.err = nil
+	p.since = time.Now()
+	log.Printf("%s is available", p.name)
+	return true
+}
+
+// Registry keeps track of all peripherals of the station
+type Registry struct {
+	mu       sync.Mutex
+	entries  []Entry
+	onChange func(Status)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add registers peripherals. They are opened in the order they were added, so a
+// peripheral should be added after the ones its open function depends on.
+func (r *Registry) Add(entries ...Entry) {
+	r.mu.Lock()
+	r.entries = append(r.entries, entries...)
+	r.mu.Unlock()
+	for _, entry := range entries {
+		entry.watch(r.changed)
+	}
+}
+
+// OnChange registers fn to run whenever a peripheral becomes available or
+// unavailable, for example when it shows up on a retry or fails to reopen
+func (r *Registry) OnChange(fn func(Status)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = fn
+}
+
+func (r *Registry) changed(status Status) {
+	r.mu.Lock()
+	fn := r.onChange
+	r.mu.Unlock()
+	if fn != nil {
+		fn(status)
+	}
+}
+
+func (r *Registry) list() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// OpenAll tries to open every peripheral that isn't available yet
+func (r *Registry) OpenAll() {
+	for _, entry := range r.list() {
+		entry.Open()
+	}
+}
+
+func (r *Registry) Statuses() []Status {
+	entries := r.list()
+	statuses := make([]Status, len(entries))
+	for i, entry := range entries {
+		statuses[i] = entry.Status()
+	}
+	return statuses
+}
+
+// ProbeAll checks every available peripheral, so one that was unplugged is
+// reported as lost without waiting for a route to fail on it
+func (r *Registry) ProbeAll() {
+	for _, entry := range r.list() {
+		entry.Probe()
+	}
+}
+
+// RetryInBackground periodically probes the available peripherals and retries
+// missing ones until ctx is done. interval is asked again before every wait, so
+// it may change at runtime.
+func (r *Registry) RetryInBackground(ctx context.Context, interval func() time.Duration) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval()):
+				r.ProbeAll()
+				r.OpenAll()
+			}
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package peripherals
+
+import (
+	"errors"
+	"testing"
+)
+
+// A peripheral that stops answering its probe is released and reported lost,
+// and the next retry opens it again
+func TestFailedProbeReleasesPeripheral(t *testing.T) {
+	opened, closed := 0, 0
+	var probeErr error
+	p := New("thing", func() (int, error) {
+		opened++
+		return opened, nil
+	}).WithClose(func(int) error {
+		closed++
+		return nil
+	}).WithProbe(func(int) error {
+		return probeErr
+	})
+
+	var changes []Status
+	registry := NewRegistry()
+	registry.Add(p)
+	registry.OpenAll()
+	registry.OnChange(func(status Status) { changes = append(changes, status) })
+
+	registry.ProbeAll()
+	if _, ok := p.Get(); !ok || closed != 0 || len(changes) != 0 {
+		t.Fatalf("a passing probe changed the peripheral: available = %v, closed %d times, %d changes", ok, closed, len(changes))
+	}
+
+	probeErr = errors.New("unplugged")
+	registry.ProbeAll()
+	if _, ok := p.Get(); ok {
+		t.Fatal("the peripheral is still available after a failed probe")
+	}
+	if closed != 1 {
+		t.Errorf("the peripheral was closed %d times, want once", closed)
+	}
+	if len(changes) != 1 || changes[0].Available || changes[0].Error != "unplugged" {
+		t.Fatalf("changes = %+v, want one lost status with the probe error", changes)
+	}
+
+	probeErr = nil
+	registry.OpenAll()
+	if value, ok := p.Get(); !ok || value != 2 {
+		t.Fatalf("Get() = %d, %v after the retry, want the reopened peripheral", value, ok)
+	}
+	if len(changes) != 2 || !changes[1].Available {
+		t.Errorf("changes = %+v, want a recovered status", changes)
+	}
+}
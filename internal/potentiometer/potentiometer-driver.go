@@ -10,6 +10,9 @@ import (
 	"periph.io/x/host/v3"
 )
 
+// DefaultAddress is the address of the MAX5395 on the lab board
+const DefaultAddress = addrGND
+
 // DeviceAddress represents the available I2C addresses for MAX5395
 const (
 	addrGND  = 0x28 // ADDR0 connected to GND
@@ -46,8 +49,8 @@ type driverMAX5395 struct {
 	addr uint16
 }
 
-// New creates a new MAX5395 device using the provided I2C bus and address
-func newDriver(busName string, addr uint16) (*driverMAX5395, error) {
+// OpenBus initializes the host drivers and opens the named I2C bus
+func OpenBus(busName string) (i2c.BusCloser, error) {
 
 	_, err := host.Init()
 	if err != nil {
@@ -62,17 +65,19 @@ func newDriver(busName string, addr uint16) (*driverMAX5395, error) {
 
 	fmt.Println("Driver registry initialized")
 
-	// Open the I2C bus
 	bus, err := i2creg.Open(busName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open I2C bus %s: %v", busName, err)
 	}
+	return bus, nil
+}
 
-	// Create device
+// newDriver creates a new MAX5395 device on the provided I2C bus and address
+func newDriver(bus i2c.Bus, addr uint16) *driverMAX5395 {
 	return &driverMAX5395{
 		dev:  i2c.Dev{Bus: bus, Addr: addr},
 		addr: addr,
-	}, nil
+	}
 }
 
 // SetWiper sets the wiper position (0-255)
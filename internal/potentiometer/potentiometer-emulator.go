@@ -0,0 +1,154 @@
+package potentiometer
+
+import (
+	"fmt"
+	"sync"
+
+	"periph.io/x/conn/v3/physic"
+)
+
+// Configuration register bits, as decoded by getConfiguration
+const (
+	configChargePump = 0x80
+	configHOpen      = 0x10
+	configLOpen      = 0x08
+	configWOpen      = 0x04
+	configTapMask    = 0x03
+)
+
+// Tap select values of the configuration register
+const (
+	tapWiperRegister = 0
+	tapZero          = 1
+	tapMid           = 2
+	tapFull          = 3
+)
+
+const wiperMidscale = 0x80
+
+// EmulatedMAX5395 is an in-memory MAX5395 that implements the periph i2c.Bus
+// interface, so the driver can run without /dev/i2c-*. It answers only at its own
+// address and keeps the wiper and configuration registers the way the chip does.
+type EmulatedMAX5395 struct {
+	mu      sync.Mutex
+	addr    uint16
+	wiper   uint8
+	config  uint8
+	pointer uint8
+	speed   physic.Frequency
+}
+
+// NewEmulatedMAX5395 returns a device in its power-on state: wiper at midscale,
+// charge pump enabled and no terminal open
+func NewEmulatedMAX5395(addr uint16) *EmulatedMAX5395 {
+	e := &EmulatedMAX5395{addr: addr}
+	e.reset()
+	return e
+}
+
+func (e *EmulatedMAX5395) reset() {
+	e.wiper = wiperMidscale
+	e.config = configChargePump
+	e.pointer = cmdReadWiper
+}
+
+func (e *EmulatedMAX5395) String() string {
+	return fmt.Sprintf("MAX5395 emulator at 0x%02X", e.addr)
+}
+
+func (e *EmulatedMAX5395) SetSpeed(f physic.Frequency) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.speed = f
+	return nil
+}
+
+// Tx handles one bus transaction. A write starts with a command byte; a lone
+// read command byte only selects the register returned by the following read.
+func (e *EmulatedMAX5395) Tx(addr uint16, w, r []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if addr != e.addr {
+		return fmt.Errorf("i2c: no device acknowledged address 0x%02X", addr)
+	}
+
+	if len(w) > 0 {
+		if err := e.command(w[0], w[1:]); err != nil {
+			return err
+		}
+	}
+
+	for i := range r {
+		switch e.pointer {
+		case cmdReadConfig:
+			r[i] = e.config
+		default:
+			r[i] = e.wiper
+		}
+	}
+	return nil
+}
+
+func (e *EmulatedMAX5395) command(cmd byte, data []byte) error {
+	switch cmd {
+	case cmdWiper:
+		// Same byte as cmdReadWiper: without data it only selects the wiper register
+		if len(data) == 0 {
+			e.pointer = cmdReadWiper
+			return nil
+		}
+		e.wiper = data[0]
+	case cmdReadConfig:
+		e.pointer = cmdReadConfig
+	case cmdSDClr:
+		e.config &^= configHOpen | configLOpen | configWOpen | configTapMask
+	case cmdSDHWreg, cmdSDHZero, cmdSDHMid, cmdSDHFull:
+		e.config = (e.config &^ configTapMask) | configHOpen | (cmd & configTapMask)
+	case cmdSDLWreg, cmdSDLZero, cmdSDLMid, cmdSDLFull:
+		e.config = (e.config &^ configTapMask) | configLOpen | (cmd & configTapMask)
+	case cmdSDW:
+		e.config |= configWOpen
+	case cmdQPOff:
+		e.config &^= configChargePump
+	case cmdQPOn:
+		e.config |= configChargePump
+	case cmdRST:
+		e.reset()
+	default:
+		return fmt.Errorf("i2c: MAX5395 does not acknowledge command 0x%02X", cmd)
+	}
+	return nil
+}
+
+// WiperRegister returns the value last written to the wiper register
+func (e *EmulatedMAX5395) WiperRegister() uint8 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.wiper
+}
+
+// ConfigRegister returns the raw configuration register
+func (e *EmulatedMAX5395) ConfigRegister() uint8 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.config
+}
+
+// Tap returns the tap the wiper actually sits on, which a shutdown command can
+// override without touching the wiper register
+func (e *EmulatedMAX5395) Tap() uint8 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch e.config & configTapMask {
+	case tapZero:
+		return 0
+	case tapMid:
+		return wiperMidscale
+	case tapFull:
+		return 0xFF
+	default:
+		return e.wiper
+	}
+}
@@ -0,0 +1,193 @@
+package potentiometer
+
+import "testing"
+
+func newEmulatedDriver(t *testing.T) (*driverMAX5395, *EmulatedMAX5395) {
+	t.Helper()
+	emulator := NewEmulatedMAX5395(DefaultAddress)
+	return newDriver(emulator, DefaultAddress), emulator
+}
+
+func TestWiperWriteReadBack(t *testing.T) {
+	for _, position := range []uint8{0x00, 0x01, 0x42, wiperMidscale, 0xFE, 0xFF} {
+		driver, emulator := newEmulatedDriver(t)
+
+		if err := driver.setWiper(position); err != nil {
+			t.Fatal(err)
+		}
+		if got := emulator.WiperRegister(); got != position {
+			t.Errorf("wiper register = 0x%02X after writing 0x%02X", got, position)
+		}
+		if got, err := driver.getWiper(); err != nil || got != position {
+			t.Errorf("getWiper() = 0x%02X, %v, want 0x%02X", got, err, position)
+		}
+		if got := emulator.Tap(); got != position {
+			t.Errorf("tap = 0x%02X, want the wiper register 0x%02X", got, position)
+		}
+	}
+}
+
+// A shutdown command may move the tap without touching the wiper register, and
+// clearing the shutdown puts the tap back on the register value
+func TestShutdownOverridesTap(t *testing.T) {
+	const position = 0x42
+
+	tests := []struct {
+		name       string
+		shutdown   func(d *driverMAX5395) error
+		wantTap    uint8
+		wantConfig uint8
+	}{
+		{"H wiper", func(d *driverMAX5395) error { return d.shutdownH("wiper") }, position, configHOpen},
+		{"H zero", func(d *driverMAX5395) error { return d.shutdownH("zero") }, 0x00, configHOpen | tapZero},
+		{"H mid", func(d *driverMAX5395) error { return d.shutdownH("mid") }, wiperMidscale, configHOpen | tapMid},
+		{"H full", func(d *driverMAX5395) error { return d.shutdownH("full") }, 0xFF, configHOpen | tapFull},
+		{"L wiper", func(d *driverMAX5395) error { return d.shutdownL("wiper") }, position, configLOpen},
+		{"L zero", func(d *driverMAX5395) error { return d.shutdownL("zero") }, 0x00, configLOpen | tapZero},
+		{"L mid", func(d *driverMAX5395) error { return d.shutdownL("mid") }, wiperMidscale, configLOpen | tapMid},
+		{"L full", func(d *driverMAX5395) error { return d.shutdownL("full") }, 0xFF, configLOpen | tapFull},
+		{"W", func(d *driverMAX5395) error { return d.shutdownW() }, position, configWOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, emulator := newEmulatedDriver(t)
+			if err := driver.setWiper(position); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.shutdown(driver); err != nil {
+				t.Fatal(err)
+			}
+			if got := emulator.Tap(); got != tt.wantTap {
+				t.Errorf("tap = 0x%02X, want 0x%02X", got, tt.wantTap)
+			}
+			if got, want := emulator.ConfigRegister(), configChargePump|tt.wantConfig; got != want {
+				t.Errorf("config = %08b, want %08b", got, want)
+			}
+			if got := emulator.WiperRegister(); got != position {
+				t.Errorf("shutdown changed the wiper register to 0x%02X", got)
+			}
+
+			if err := driver.clearShutdown(); err != nil {
+				t.Fatal(err)
+			}
+			if got := emulator.Tap(); got != position {
+				t.Errorf("tap = 0x%02X after clearing the shutdown, want 0x%02X", got, position)
+			}
+			if got := emulator.ConfigRegister(); got != configChargePump {
+				t.Errorf("config = %08b after clearing the shutdown, want %08b", got, configChargePump)
+			}
+		})
+	}
+}
+
+func TestInvalidShutdownPositionSendsNothing(t *testing.T) {
+	driver, emulator := newEmulatedDriver(t)
+
+	if err := driver.shutdownH("half"); err == nil {
+		t.Error("shutdownH accepted an unknown position")
+	}
+	if err := driver.shutdownL("half"); err == nil {
+		t.Error("shutdownL accepted an unknown position")
+	}
+	if got := emulator.ConfigRegister(); got != configChargePump {
+		t.Errorf("config = %08b, want the power-on value", got)
+	}
+}
+
+func TestChargePump(t *testing.T) {
+	tests := []struct {
+		name    string
+		toggle  func(d *driverMAX5395) error
+		enabled bool
+	}{
+		{"off", (*driverMAX5395).disableChargePump, false},
+		{"on", (*driverMAX5395).enableChargePump, true},
+	}
+
+	driver, emulator := newEmulatedDriver(t)
+	if err := driver.shutdownW(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if err := tt.toggle(driver); err != nil {
+			t.Fatal(err)
+		}
+		config, err := driver.getConfiguration()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.ChargePumpEnabled != tt.enabled {
+			t.Errorf("%s: ChargePumpEnabled = %v", tt.name, config.ChargePumpEnabled)
+		}
+		// The charge pump bit leaves the shutdown state alone
+		if !config.WTerminalOpen || emulator.ConfigRegister()&configWOpen == 0 {
+			t.Errorf("%s: the W terminal was reconnected", tt.name)
+		}
+	}
+}
+
+func TestResetReturnsToMidscale(t *testing.T) {
+	driver, emulator := newEmulatedDriver(t)
+	for _, step := range []func() error{
+		func() error { return driver.setWiper(0x10) },
+		func() error { return driver.shutdownL("full") },
+		driver.shutdownW,
+		driver.disableChargePump,
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := driver.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if got := emulator.WiperRegister(); got != wiperMidscale {
+		t.Errorf("wiper register = 0x%02X after a reset, want 0x%02X", got, wiperMidscale)
+	}
+	if got := emulator.Tap(); got != wiperMidscale {
+		t.Errorf("tap = 0x%02X after a reset, want 0x%02X", got, wiperMidscale)
+	}
+	if got := emulator.ConfigRegister(); got != configChargePump {
+		t.Errorf("config = %08b after a reset, want %08b", got, configChargePump)
+	}
+	// The reset also points reads back at the wiper register
+	if got, err := driver.getWiper(); err != nil || got != wiperMidscale {
+		t.Errorf("getWiper() = 0x%02X, %v after a reset", got, err)
+	}
+}
+
+// The driver-level reset also has to bring the reported percentage back
+func TestPotentiometerResetReportsMidscale(t *testing.T) {
+	emulator := NewEmulatedMAX5395(DefaultAddress)
+	p, err := NewPotentiometer(emulator, DefaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.SetResistancePercentage(20); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if got := emulator.WiperRegister(); got != wiperMidscale {
+		t.Errorf("wiper register = 0x%02X after a reset, want 0x%02X", got, wiperMidscale)
+	}
+	if got, want := p.GetResistancePercentage(), calculateResistancePercentageForTap(wiperMidscale); got != want {
+		t.Errorf("GetResistancePercentage() = %d after a reset, want %d", got, want)
+	}
+}
+
+func TestEmulatorAnswersOnlyItsAddress(t *testing.T) {
+	emulator := NewEmulatedMAX5395(addrNC)
+
+	if _, err := NewPotentiometer(emulator, addrGND); err == nil {
+		t.Fatal("found a MAX5395 at an address nothing answers")
+	}
+	if _, err := NewPotentiometer(emulator, addrNC); err != nil {
+		t.Fatal(err)
+	}
+}
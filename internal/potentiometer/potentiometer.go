@@ -3,29 +3,53 @@ package potentiometer
 import (
 	"fmt"
 	"math"
+
+	"periph.io/x/conn/v3/i2c"
 )
 
 const maxResistance = 10e3
 const step = maxResistance / 255
 
 type Potentiometer struct {
-	driver *driverMAX5395
+	bus         i2c.Bus
+	driver      *driverMAX5395
 	tapSelected uint8
 }
 
-func NewPotentiometer() (*Potentiometer, error) {
-	// Find the right config here
-	driver, err := newDriver("/dev/i2c-1", addrGND)
-	if err != nil {
-		return nil, err
+// NewPotentiometer takes ownership of the bus, which is closed by Close when it is an i2c.BusCloser
+func NewPotentiometer(bus i2c.Bus, addr uint16) (*Potentiometer, error) {
+	driver := newDriver(bus, addr)
+
+	// Make sure something answers at the address before handing out the device
+	if _, err := driver.getConfiguration(); err != nil {
+		return nil, fmt.Errorf("no MAX5395 at 0x%02X on %s: %w", addr, bus, err)
 	}
 
 	return &Potentiometer{
-		driver: driver,
+		bus:         bus,
+		driver:      driver,
 		tapSelected: 0,
 	}, nil
 }
 
+func (p *Potentiometer) Close() error {
+	if closer, ok := p.bus.(i2c.BusCloser); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Probe checks that the MAX5395 still answers on the bus
+func (p *Potentiometer) Probe() error {
+	_, err := p.driver.getConfiguration()
+	return err
+}
+
+// Address is the I2C address of the MAX5395
+func (p *Potentiometer) Address() uint16 {
+	return p.driver.addr
+}
+
 func (p *Potentiometer) SetResistancePercentage(percentage int) (int, error) {
 	// Clamp the percentage to the range 0-100
 	if percentage > 100 {
@@ -54,6 +78,15 @@ func (p *Potentiometer) GetResistancePercentage() int {
 	return calculateResistancePercentageForTap(p.tapSelected)
 }
 
+// Reset returns the MAX5395 to its power-on state with the wiper at midscale
+func (p *Potentiometer) Reset() error {
+	if err := p.driver.Reset(); err != nil {
+		return err
+	}
+	p.tapSelected = 0x80
+	return nil
+}
+
 func calculateClosestTapForResistancePercentage(percentage int) uint8 {
 	taps := uint8(255 * percentage / 100)
 	fmt.Println("calculated taps: ", taps, "for percentage: ", percentage)
@@ -0,0 +1,90 @@
+package stationstate
+
+import (
+	currentsession "digitrans-lab-go/internal/current-session"
+	"digitrans-lab-go/internal/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// State is what the station remembers across restarts of the backend
+type State struct {
+	Session           *currentsession.SessionState `json:"session,omitempty"`
+	Reservations      []currentsession.Reservation `json:"reservations,omitempty"`
+	ActiveReservation string                       `json:"activeReservation,omitempty"`
+	DeviceType        string                       `json:"deviceType,omitempty"`
+	// Outbox holds the events the master server hasn't accepted yet
+	Outbox []webhook.Event `json:"outbox,omitempty"`
+}
+
+// Store keeps the state in a JSON file, rewritten on every update. With an
+// empty path it only keeps it in memory.
+type Store struct {
+	path  string
+	mu    sync.Mutex
+	state State
+}
+
+// Open loads the state saved at path. A missing file is an empty state.
+func Open(path string) (*Store, error) {
+	store := &Store{path: path}
+	if path == "" {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(content, &store.state); err != nil {
+		return store, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return store, nil
+}
+
+func (s *Store) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Update changes the state with fn and saves it
+func (s *Store) Update(fn func(state *State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(&s.state)
+	if s.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write a temporary file and rename it, so a crash never leaves half a file behind
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("error saving %s: %w", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving %s: %w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving %s: %w", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("error saving %s: %w", s.path, err)
+	}
+	return nil
+}
@@ -1,25 +1,90 @@
 package stm32flash
 
 import (
+	"bytes"
+	"context"
+	"digitrans-lab-go/internal/config"
+	"digitrans-lab-go/internal/flasher"
 	"fmt"
-	"os/exec"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
-var flashMutex sync.Mutex
+// How long the tools may run before they are killed. Tests shorten them.
+var (
+	flashTimeout   = 2 * time.Minute
+	commandTimeout = 30 * time.Second
+)
 
-func Flash(filePath string) error {
-	if !flashMutex.TryLock() {
-		return fmt.Errorf("flash is already in progress")
-	}
-	defer flashMutex.Unlock()
+// st-flash reports progress as e.g. "12/64 pages written"
+var pagesWrittenPattern = regexp.MustCompile(`(\d+)/\s*(\d+) pages written`)
+
+// st-info --probe lists "chipid: 0x0000" when the ST-LINK sees no target
+var chipIDPattern = regexp.MustCompile(`chipid:\s+0x([0-9a-fA-F]+)`)
+
+// STFlash programs an STM32 over ST-LINK with the st-flash and st-info tools
+type STFlash struct {
+	runner     flasher.Runner
+	flashMutex sync.Mutex
+
+	mu          sync.Mutex
+	stFlashPath string
+	stInfoPath  string
+}
+
+func NewSTFlash(runner flasher.Runner, cfg config.MCUConfig) *STFlash {
+	s := &STFlash{runner: runner}
+	s.Configure(cfg)
+	return s
+}
+
+// Configure changes the tool paths. Operations already running keep the old ones.
+func (s *STFlash) Configure(cfg config.MCUConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stFlashPath = cfg.STFlashPath
+	s.stInfoPath = cfg.STInfoPath
+}
 
-	result, err := runCommand("st-flash", "--reset", "--format", "ihex", "write", filePath)
+func (s *STFlash) tools() (stFlash, stInfo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stFlashPath, s.stInfoPath
+}
+
+func (s *STFlash) Flash(filePath string, progress flasher.ProgressFunc) error {
+	if !s.flashMutex.TryLock() {
+		return flasher.ErrBusy
+	}
+	defer s.flashMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), flashTimeout)
+	defer cancel()
+
+	stFlash, _ := s.tools()
+	result, err := flasher.RunCollect(ctx, s.runner, flasher.Command{
+		Name: stFlash,
+		Args: []string{"--reset", "--format", "ihex", "write", filePath},
+	}, func(line flasher.Line) bool {
+		if progress != nil {
+			if m := pagesWrittenPattern.FindStringSubmatch(line.Text); m != nil {
+				done, _ := strconv.Atoi(m[1])
+				total, _ := strconv.Atoi(m[2])
+				if total > 0 {
+					progress(done * 100 / total)
+				}
+			}
+		}
+		return true
+	})
 	if err != nil {
 		return fmt.Errorf("failed to run command: %w", err)
 	}
-	if strings.Contains(result, "ERROR") || strings.Contains(result, "Failed") {
+	if hasError(result) {
 		return fmt.Errorf("flash failed: %s", result)
 	}
 	fmt.Println("Flash successful")
@@ -27,27 +92,172 @@ func Flash(filePath string) error {
 	return nil
 }
 
-func Reset() error {
-	result, err := runCommand("st-flash", "reset")
+// Verify reads the flash back and compares it with the Intel HEX image
+func (s *STFlash) Verify(filePath string) error {
+	base, image, mask, err := readIntelHex(filePath)
+	if err != nil {
+		return err
+	}
+
+	readBack, err := os.CreateTemp("", "stm32-verify-*.bin")
+	if err != nil {
+		return fmt.Errorf("failed to create read-back file: %w", err)
+	}
+	readBack.Close()
+	defer os.Remove(readBack.Name())
+
+	if !s.flashMutex.TryLock() {
+		return flasher.ErrBusy
+	}
+	defer s.flashMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), flashTimeout)
+	defer cancel()
+
+	stFlash, _ := s.tools()
+	result, err := flasher.RunCollect(ctx, s.runner, flasher.Command{
+		Name: stFlash,
+		Args: []string{"--format", "binary", "read", readBack.Name(), fmt.Sprintf("0x%08x", base), strconv.Itoa(len(image))},
+	}, nil)
 	if err != nil {
 		return fmt.Errorf("failed to run command: %w", err)
 	}
-	if strings.Contains(result, "ERROR") || strings.Contains(result, "Failed") {
+	if hasError(result) {
+		return fmt.Errorf("read-back failed: %s", result)
+	}
+
+	flash, err := os.ReadFile(readBack.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read back flash: %w", err)
+	}
+	if len(flash) < len(image) {
+		return fmt.Errorf("read back %d bytes, expected %d", len(flash), len(image))
+	}
+	for i := range image {
+		if mask[i] && flash[i] != image[i] {
+			return fmt.Errorf("flash differs from image at 0x%08x: got 0x%02x, want 0x%02x", base+uint32(i), flash[i], image[i])
+		}
+	}
+	return nil
+}
+
+func (s *STFlash) Reset() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	stFlash, _ := s.tools()
+	result, err := flasher.RunCollect(ctx, s.runner, flasher.Command{Name: stFlash, Args: []string{"reset"}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to run command: %w", err)
+	}
+	if hasError(result) {
 		return fmt.Errorf("reset failed: %s", result)
 	}
 	return nil
 }
 
-func runCommand(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
-	fmt.Println("Running command:", cmd.String())
-	result, err := cmd.CombinedOutput()
-	strResult := string(result)
-	fmt.Println("Command result:", strResult)
+// Probe checks that an ST-LINK is attached and can see a chip
+func (s *STFlash) Probe() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	_, stInfo := s.tools()
+	result, err := flasher.RunCollect(ctx, s.runner, flasher.Command{Name: stInfo, Args: []string{"--probe"}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to run command: %w", err)
+	}
+	if hasError(result) || strings.Contains(result, "Found 0 stlink programmers") {
+		return fmt.Errorf("no ST-LINK found: %s", result)
+	}
+	m := chipIDPattern.FindStringSubmatch(result)
+	if m == nil {
+		return fmt.Errorf("ST-LINK does not see a chip: %s", result)
+	}
+	if chipID, _ := strconv.ParseUint(m[1], 16, 32); chipID == 0 {
+		return fmt.Errorf("ST-LINK does not see a chip: %s", result)
+	}
+	return nil
+}
+
+func hasError(result string) bool {
+	return strings.Contains(result, "ERROR") || strings.Contains(result, "Failed")
+}
+
+// readIntelHex loads an Intel HEX file into a contiguous image starting at base.
+// mask marks the bytes that the file actually defines.
+func readIntelHex(filePath string) (base uint32, image []byte, mask []bool, err error) {
+	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to run command: %w", err)
+		return 0, nil, nil, fmt.Errorf("failed to read firmware: %w", err)
+	}
+
+	data := map[uint32]byte{}
+	var upper uint32
+	for n, rawLine := range bytes.Split(content, []byte("\n")) {
+		line := strings.TrimSpace(string(rawLine))
+		if line == "" {
+			continue
+		}
+		if line[0] != ':' || len(line) < 11 || len(line)%2 == 0 {
+			return 0, nil, nil, fmt.Errorf("line %d: not an Intel HEX record", n+1)
+		}
+		record := make([]byte, (len(line)-1)/2)
+		for i := range record {
+			b, err := strconv.ParseUint(line[1+2*i:3+2*i], 16, 8)
+			if err != nil {
+				return 0, nil, nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			record[i] = byte(b)
+		}
+		length := int(record[0])
+		if len(record) != length+5 {
+			return 0, nil, nil, fmt.Errorf("line %d: record length mismatch", n+1)
+		}
+		var sum byte
+		for _, b := range record {
+			sum += b
+		}
+		if sum != 0 {
+			return 0, nil, nil, fmt.Errorf("line %d: bad checksum", n+1)
+		}
+
+		offset := uint32(record[1])<<8 | uint32(record[2])
+		payload := record[4 : 4+length]
+		switch record[3] {
+		case 0x00:
+			for i, b := range payload {
+				data[upper+offset+uint32(i)] = b
+			}
+		case 0x01:
+			// End of file
+		case 0x02:
+			if length != 2 {
+				return 0, nil, nil, fmt.Errorf("line %d: bad segment address record", n+1)
+			}
+			upper = (uint32(payload[0])<<8 | uint32(payload[1])) << 4
+		case 0x04:
+			if length != 2 {
+				return 0, nil, nil, fmt.Errorf("line %d: bad linear address record", n+1)
+			}
+			upper = (uint32(payload[0])<<8 | uint32(payload[1])) << 16
+		}
+	}
+	if len(data) == 0 {
+		return 0, nil, nil, fmt.Errorf("firmware contains no data")
+	}
+
+	first, last := ^uint32(0), uint32(0)
+	for addr := range data {
+		first = min(first, addr)
+		last = max(last, addr)
+	}
+	image = make([]byte, last-first+1)
+	mask = make([]bool, len(image))
+	for addr, b := range data {
+		image[addr-first] = b
+		mask[addr-first] = true
 	}
-	return strResult, nil
+	return first, image, mask, nil
 }
 
 // DEPRECATED VERSION:
@@ -0,0 +1,67 @@
+package stm32flash
+
+import (
+	"context"
+	"digitrans-lab-go/internal/config"
+	"digitrans-lab-go/internal/flasher"
+	"errors"
+	"testing"
+	"time"
+)
+
+func shortenTimeouts(t *testing.T) {
+	t.Helper()
+	flash, command := flashTimeout, commandTimeout
+	flashTimeout, commandTimeout = 100*time.Millisecond, 100*time.Millisecond
+	t.Cleanup(func() { flashTimeout, commandTimeout = flash, command })
+}
+
+func newTestSTFlash(runner flasher.Runner) *STFlash {
+	return NewSTFlash(runner, config.MCUConfig{STFlashPath: "st-flash", STInfoPath: "st-info"})
+}
+
+// A hung st-flash is killed at the timeout, and the next flash isn't refused as busy
+func TestFlashTimesOutOnHungTool(t *testing.T) {
+	shortenTimeouts(t)
+	runner := flasher.NewFakeRunner()
+	runner.Add("st-flash",
+		flasher.Script{Lines: []flasher.Line{{Text: "16/64 pages written"}}, Hang: true},
+		flasher.Script{Lines: []flasher.Line{{Text: "64/64 pages written"}}},
+	)
+	s := newTestSTFlash(runner)
+
+	var progress []int
+	started := time.Now()
+	err := s.Flash("firmware.hex", func(percent int) { progress = append(progress, percent) })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Flash = %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("Flash returned after %s", elapsed)
+	}
+	if len(progress) != 1 || progress[0] != 25 {
+		t.Errorf("progress = %v, want [25] before the tool hung", progress)
+	}
+
+	if err := s.Flash("firmware.hex", nil); err != nil {
+		t.Fatalf("flashing after the timeout: %v", err)
+	}
+}
+
+func TestResetAndProbeTimeOutOnHungTool(t *testing.T) {
+	shortenTimeouts(t)
+	runner := flasher.NewFakeRunner()
+	runner.Add("st-flash", flasher.Script{Hang: true})
+	runner.Add("st-info", flasher.Script{Lines: []flasher.Line{{Text: "Found 1 stlink programmers"}}, Hang: true})
+	s := newTestSTFlash(runner)
+
+	if err := s.Reset(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Reset = %v, want a deadline error", err)
+	}
+	if err := s.Probe(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Probe = %v, want a deadline error", err)
+	}
+	if commands := runner.Commands(); len(commands) != 2 || commands[0].String() != "st-flash reset" || commands[1].String() != "st-info --probe" {
+		t.Errorf("commands = %v", commands)
+	}
+}
@@ -12,6 +12,8 @@ type Timer struct {
 	callback func()
 	mu       sync.Mutex
 	active   bool
+	// generation tells a callback of an earlier Start apart from the current one
+	generation uint64
 }
 
 func NewTimer(duration time.Duration, callback func()) *Timer {
@@ -36,14 +38,20 @@ func (t *Timer) Start(callback func()) {
 
 	t.active = true
 	t.callback = callback
+	t.generation++
+	generation := t.generation
 	t.timer = time.AfterFunc(t.duration, func() {
 		fmt.Println("Timer expired")
+		// The callback runs unlocked, so it may take long and Stop or Start the timer
 		t.mu.Lock()
-		if t.active {
-			t.callback()
+		fire := t.active && t.generation == generation
+		if fire {
 			t.active = false
 		}
 		t.mu.Unlock()
+		if fire {
+			callback()
+		}
 	})
 }
 
@@ -0,0 +1,98 @@
+package uart
+
+import (
+	"sync"
+	"time"
+)
+
+// Chunk is one read from the UART. Seq grows by one per chunk and never goes
+// back, also across sessions, so a client can say where it left off.
+type Chunk struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Data []byte    `json:"-"`
+}
+
+// Buffer keeps the latest UART output, up to a number of bytes, so clients that
+// reconnect can catch up on what they missed
+type Buffer struct {
+	mu      sync.Mutex
+	chunks  []Chunk
+	size    int
+	limit   int
+	lastSeq uint64
+	// changed is closed and replaced on every append
+	changed chan struct{}
+}
+
+func NewBuffer(limit int) *Buffer {
+	return &Buffer{limit: limit, changed: make(chan struct{})}
+}
+
+// SetLimit changes how many bytes are kept, dropping the oldest chunks if needed
+func (b *Buffer) SetLimit(limit int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+	b.trim()
+}
+
+// Append stores a copy of data as the next chunk
+func (b *Buffer) Append(data []byte) Chunk {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastSeq++
+	chunk := Chunk{Seq: b.lastSeq, Time: time.Now(), Data: append([]byte(nil), data...)}
+	b.chunks = append(b.chunks, chunk)
+	b.size += len(chunk.Data)
+	b.trim()
+
+	close(b.changed)
+	b.changed = make(chan struct{})
+	return chunk
+}
+
+// Clear drops everything buffered so far, the sequence carries on
+func (b *Buffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.chunks = nil
+	b.size = 0
+}
+
+// Since returns the chunks after seq, and whether any chunk after seq was
+// already dropped. The channel is closed once a newer chunk is appended.
+func (b *Buffer) Since(seq uint64) (chunks []Chunk, dropped bool, changed <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, chunk := range b.chunks {
+		if chunk.Seq > seq {
+			chunks = append(chunks, b.chunks[i:]...)
+			break
+		}
+	}
+	first := b.lastSeq + 1
+	if len(b.chunks) > 0 {
+		first = b.chunks[0].Seq
+	}
+	return chunks, seq+1 < first && seq < b.lastSeq, b.changed
+}
+
+// LastSeq is the sequence number of the latest chunk, 0 before the first one
+func (b *Buffer) LastSeq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastSeq
+}
+
+// trim must be called with b.mu held. The latest chunk is always kept.
+func (b *Buffer) trim() {
+	drop := 0
+	for drop < len(b.chunks)-1 && b.size > b.limit {
+		b.size -= len(b.chunks[drop].Data)
+		drop++
+	}
+	b.chunks = b.chunks[drop:]
+}
@@ -0,0 +1,58 @@
+package uart
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// How text from a client becomes bytes on the UART, and how the UART output is
+// sent back. Binary WebSocket frames carry exact bytes whatever the mode.
+const (
+	// ModeLine adds the line ending to each text and sends the output as text
+	ModeLine = "line"
+	// ModeRaw writes texts as they are and sends the output as binary frames
+	ModeRaw = "raw"
+	// ModeHex takes and sends hex strings
+	ModeHex = "hex"
+)
+
+// LineEndings are the terminators of ModeLine by name
+var LineEndings = map[string]string{
+	"lf":   "\n",
+	"crlf": "\r\n",
+	"cr":   "\r",
+	"none": "",
+}
+
+// Framing is the mode of one client
+type Framing struct {
+	Mode       string `json:"mode"`
+	LineEnding string `json:"lineEnding"`
+}
+
+func (f Framing) Validate() error {
+	if f.Mode != ModeLine && f.Mode != ModeRaw && f.Mode != ModeHex {
+		return fmt.Errorf("unknown UART mode %q, use line, raw or hex", f.Mode)
+	}
+	if _, ok := LineEndings[f.LineEnding]; !ok {
+		return fmt.Errorf("unknown line ending %q, use lf, crlf, cr or none", f.LineEnding)
+	}
+	return nil
+}
+
+// Encode turns text sent by a client into the bytes for the UART
+func (f Framing) Encode(text string) ([]byte, error) {
+	switch f.Mode {
+	case ModeRaw:
+		return []byte(text), nil
+	case ModeHex:
+		data, err := hex.DecodeString(strings.Join(strings.Fields(text), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex: %w", err)
+		}
+		return data, nil
+	default:
+		return []byte(text + LineEndings[f.LineEnding]), nil
+	}
+}
@@ -0,0 +1,147 @@
+package uart
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// byIDDir holds the links udev makes for serial devices, named after their USB identity
+const byIDDir = "/dev/serial/by-id"
+
+// PortInfo describes a serial port the system reports
+type PortInfo struct {
+	Name         string `json:"name"`
+	USB          bool   `json:"usb"`
+	VID          string `json:"vid,omitempty"`
+	PID          string `json:"pid,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	Product      string `json:"product,omitempty"`
+	// ByID are the /dev/serial/by-id links to the port, stable across reboots
+	ByID []string `json:"byId,omitempty"`
+}
+
+// ListPorts returns the serial ports the system reports, with their USB details
+func ListPorts() ([]PortInfo, error) {
+	details, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ports: %w", err)
+	}
+
+	links := byIDLinks()
+	var ports []PortInfo
+	for _, port := range details {
+		// Ports the enumerator knows nothing about come without a name
+		if port.Name == "" {
+			continue
+		}
+		ports = append(ports, PortInfo{
+			Name:         port.Name,
+			USB:          port.IsUSB,
+			VID:          port.VID,
+			PID:          port.PID,
+			SerialNumber: port.SerialNumber,
+			Product:      port.Product,
+			ByID:         links[port.Name],
+		})
+	}
+	return ports, nil
+}
+
+// byIDLinks maps device paths to the by-id links pointing at them
+func byIDLinks() map[string][]string {
+	links := map[string][]string{}
+	entries, err := os.ReadDir(byIDDir)
+	if err != nil {
+		return links
+	}
+	for _, entry := range entries {
+		link := filepath.Join(byIDDir, entry.Name())
+		target, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			continue
+		}
+		links[target] = append(links[target], link)
+	}
+	return links
+}
+
+// PortSelector says which serial port the UART uses: Path if set, else the USB
+// device matching those of VID, PID and SerialNumber that are set. An empty
+// selector takes the first port.
+type PortSelector struct {
+	Path         string
+	VID          string
+	PID          string
+	SerialNumber string
+}
+
+func (s PortSelector) matchesUSB() bool {
+	return s.VID != "" || s.PID != "" || s.SerialNumber != ""
+}
+
+func (s PortSelector) matches(port PortInfo) bool {
+	return port.USB &&
+		(s.VID == "" || strings.EqualFold(s.VID, port.VID)) &&
+		(s.PID == "" || strings.EqualFold(s.PID, port.PID)) &&
+		(s.SerialNumber == "" || s.SerialNumber == port.SerialNumber)
+}
+
+func (s PortSelector) String() string {
+	if s.Path != "" {
+		return s.Path
+	}
+	var parts []string
+	for _, part := range []struct{ name, value string }{{"vid", s.VID}, {"pid", s.PID}, {"serial", s.SerialNumber}} {
+		if part.value != "" {
+			parts = append(parts, part.name+"="+part.value)
+		}
+	}
+	return "USB " + strings.Join(parts, " ")
+}
+
+// Find returns the path of the selected port. It fails rather than guess when
+// several USB devices match, e.g. two adapters with the same VID and PID.
+func (s PortSelector) Find() (string, error) {
+	if s.Path != "" {
+		if _, err := os.Stat(s.Path); err != nil {
+			return "", fmt.Errorf("port %s not found: %w", s.Path, err)
+		}
+		return s.Path, nil
+	}
+
+	if !s.matchesUSB() {
+		ports, err := serial.GetPortsList()
+		if err != nil {
+			return "", fmt.Errorf("failed to list ports: %w", err)
+		}
+		if len(ports) == 0 {
+			return "", fmt.Errorf("no serial ports found")
+		}
+		return ports[0], nil
+	}
+
+	ports, err := ListPorts()
+	if err != nil {
+		return "", err
+	}
+
+	var found []string
+	for _, port := range ports {
+		if s.matches(port) {
+			found = append(found, port.Name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no serial port matches %s", s)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("several serial ports match %s: %s, set uart.serial_number or uart.port", s, strings.Join(found, ", "))
+	}
+}
@@ -0,0 +1,119 @@
+package uart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// PTYTransport is a pseudo-terminal pair standing in for a USB-UART adapter.
+// The UART opens the slave side like any tty, so baud and line settings go through
+// termios as usual; the master side is the board's end of the wire.
+type PTYTransport struct {
+	master    *os.File
+	slavePath string
+}
+
+func NewPTYTransport() (*PTYTransport, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /dev/ptmx: %w", err)
+	}
+
+	// Use the raw descriptor without os.File.Fd, which would switch the master to blocking mode
+	rawConn, err := master.SyscallConn()
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+	var ptyNumber int
+	var ioctlErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if ioctlErr = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0); ioctlErr != nil {
+			return
+		}
+		ptyNumber, ioctlErr = unix.IoctlGetInt(int(fd), unix.TIOCGPTN)
+	})
+	if err == nil {
+		err = ioctlErr
+	}
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("failed to set up pseudo-terminal: %w", err)
+	}
+
+	return &PTYTransport{
+		master:    master,
+		slavePath: fmt.Sprintf("/dev/pts/%d", ptyNumber),
+	}, nil
+}
+
+func (t *PTYTransport) Name() string {
+	return TransportPTY + ":" + t.slavePath
+}
+
+func (t *PTYTransport) Port() string {
+	return t.slavePath
+}
+
+// SlavePath is the tty device the UART opens
+func (t *PTYTransport) SlavePath() string {
+	return t.slavePath
+}
+
+func (t *PTYTransport) Open(settings Settings) (*TTY, error) {
+	return OpenTTY(t.slavePath, settings)
+}
+
+// Peer is the board's end of the pair: reads return what the UART sent and
+// writes show up as UART input. Reads fail with EIO while the UART has the port closed.
+func (t *PTYTransport) Peer() *os.File {
+	return t.master
+}
+
+func (t *PTYTransport) Close() error {
+	return t.master.Close()
+}
+
+// Serve runs a fake MCU on the peer side until ctx is done. Every chunk the UART sends
+// is passed to respond and whatever it returns is written back.
+func (t *PTYTransport) Serve(ctx context.Context, respond func(received []byte) []byte) error {
+	buffer := make([]byte, 1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		t.master.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err := t.master.Read(buffer)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			// EIO until the UART (re)opens the slave side, e.g. during a speed change
+			if errors.Is(err, syscall.EIO) {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		reply := respond(buffer[:n])
+		if len(reply) == 0 {
+			continue
+		}
+		if _, err := t.master.Write(reply); err != nil {
+			return err
+		}
+	}
+}
+
+// Echo sends everything back the way a loopback wire would
+func Echo(received []byte) []byte {
+	return received
+}
@@ -0,0 +1,111 @@
+package uart
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+// newEchoUART opens a UART on a pseudo-terminal whose other end echoes
+// everything back, like a board running a loopback firmware
+func newEchoUART(t *testing.T) *UART {
+	t.Helper()
+	transport, err := NewPTYTransport()
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	t.Cleanup(func() { transport.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- transport.Serve(ctx, Echo) }()
+	t.Cleanup(func() {
+		cancel()
+		<-served
+	})
+
+	u := NewUART(transport, 115200)
+	if err := u.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { u.Close() })
+	return u
+}
+
+// readFor reads the UART until it got want or the time is up
+func readFor(t *testing.T, u *UART, want []byte) {
+	t.Helper()
+	var got []byte
+	buffer := make([]byte, 64)
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		n, err := u.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, buffer[:n]...)
+		if bytes.Equal(got, want) {
+			return
+		}
+	}
+	t.Fatalf("read %q, want %q", got, want)
+}
+
+func TestPTYEcho(t *testing.T) {
+	u := newEchoUART(t)
+
+	// Bytes that aren't text, including a line ending the tty must leave alone
+	data := []byte("hello\r\n\x00\xff\x7f")
+	if err := u.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	readFor(t, u, data)
+}
+
+// The peer side sees EIO while the UART reopens the port and has to keep serving
+func TestPTYEchoAfterSpeedChange(t *testing.T) {
+	u := newEchoUART(t)
+
+	if err := u.ChangeSpeed(9600); err != nil {
+		t.Fatal(err)
+	}
+	if speed := u.Info().Speed; speed != 9600 {
+		t.Fatalf("speed = %d, want 9600", speed)
+	}
+	if err := u.Write([]byte("after")); err != nil {
+		t.Fatal(err)
+	}
+	readFor(t, u, []byte("after"))
+}
+
+// A speed change is checked like any other settings change and leaves the port alone
+func TestChangeSpeedValidates(t *testing.T) {
+	u := newEchoUART(t)
+
+	for _, speed := range []int{0, -9600} {
+		if err := u.ChangeSpeed(speed); err == nil {
+			t.Errorf("ChangeSpeed(%d) succeeded", speed)
+		}
+	}
+	if speed := u.Info().Speed; speed != 115200 {
+		t.Fatalf("speed = %d after rejected changes, want 115200", speed)
+	}
+	if err := u.Write([]byte("still")); err != nil {
+		t.Fatal(err)
+	}
+	readFor(t, u, []byte("still"))
+}
+
+// The port is configured through termios on its own descriptor, flow control included
+func TestConfigureFlowControl(t *testing.T) {
+	u := newEchoUART(t)
+
+	settings := DefaultSettings(57600)
+	settings.FlowControl = FlowXONXOFF
+	if err := u.Configure(settings); err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Settings(); got != settings {
+		t.Fatalf("settings = %+v, want %+v", got, settings)
+	}
+}
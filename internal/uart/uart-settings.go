@@ -0,0 +1,105 @@
+package uart
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flow control settings
+const (
+	FlowNone    = "none"
+	FlowRTSCTS  = "rts-cts"
+	FlowXONXOFF = "xon-xoff"
+)
+
+// parities are the termios bits of each parity, mark and space are stick parities
+var parities = map[string]uint32{
+	"none":  0,
+	"odd":   unix.PARENB | unix.PARODD,
+	"even":  unix.PARENB,
+	"mark":  unix.PARENB | unix.PARODD | unix.CMSPAR,
+	"space": unix.PARENB | unix.CMSPAR,
+}
+
+// 1.5 stop bits aren't supported by termios
+var stopBits = map[float64]uint32{
+	1: 0,
+	2: unix.CSTOPB,
+}
+
+var dataBits = map[int]uint32{
+	5: unix.CS5,
+	6: unix.CS6,
+	7: unix.CS7,
+	8: unix.CS8,
+}
+
+// Settings is the serial line configuration, e.g. 8N1 at 115200 baud
+type Settings struct {
+	Baud        int     `json:"baud"`
+	DataBits    int     `json:"dataBits"`
+	Parity      string  `json:"parity"`
+	StopBits    float64 `json:"stopBits"`
+	FlowControl string  `json:"flowControl"`
+}
+
+// DefaultSettings is 8N1 without flow control at speed
+func DefaultSettings(speed int) Settings {
+	return Settings{Baud: speed, DataBits: 8, Parity: "none", StopBits: 1, FlowControl: FlowNone}
+}
+
+func (s Settings) Validate() error {
+	if s.Baud <= 0 {
+		return fmt.Errorf("baud must be positive, got %d", s.Baud)
+	}
+	if _, ok := dataBits[s.DataBits]; !ok {
+		return fmt.Errorf("dataBits must be 5, 6, 7 or 8, got %d", s.DataBits)
+	}
+	if _, ok := parities[s.Parity]; !ok {
+		return fmt.Errorf("unknown parity %q, use none, odd, even, mark or space", s.Parity)
+	}
+	if _, ok := stopBits[s.StopBits]; !ok {
+		return fmt.Errorf("stopBits must be 1 or 2, got %g", s.StopBits)
+	}
+	if s.FlowControl != FlowNone && s.FlowControl != FlowRTSCTS && s.FlowControl != FlowXONXOFF {
+		return fmt.Errorf("unknown flow control %q, use none, rts-cts or xon-xoff", s.FlowControl)
+	}
+	return nil
+}
+
+// termiosSettings decodes termios, the baud rate is taken as set
+func termiosSettings(baud int, termios *unix.Termios) Settings {
+	settings := DefaultSettings(baud)
+	switch termios.Cflag & unix.CSIZE {
+	case unix.CS5:
+		settings.DataBits = 5
+	case unix.CS6:
+		settings.DataBits = 6
+	case unix.CS7:
+		settings.DataBits = 7
+	}
+	if termios.Cflag&unix.PARENB != 0 {
+		odd := termios.Cflag&unix.PARODD != 0
+		switch {
+		case termios.Cflag&unix.CMSPAR != 0 && odd:
+			settings.Parity = "mark"
+		case termios.Cflag&unix.CMSPAR != 0:
+			settings.Parity = "space"
+		case odd:
+			settings.Parity = "odd"
+		default:
+			settings.Parity = "even"
+		}
+	}
+	if termios.Cflag&unix.CSTOPB != 0 {
+		settings.StopBits = 2
+	}
+	switch {
+	case termios.Cflag&unix.CRTSCTS != 0:
+		settings.FlowControl = FlowRTSCTS
+	case termios.Iflag&unix.IXON != 0:
+		settings.FlowControl = FlowXONXOFF
+	}
+	return settings
+}
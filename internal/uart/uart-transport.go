@@ -0,0 +1,70 @@
+package uart
+
+import (
+	"fmt"
+	"slices"
+
+	"go.bug.st/serial"
+)
+
+// Transport opens the serial link the UART talks over
+type Transport interface {
+	Open(settings Settings) (*TTY, error)
+	Name() string
+	// Port is the path of the port opened last, or empty before the first Open
+	Port() string
+}
+
+const (
+	TransportSerial = "serial"
+	TransportPTY    = "pty"
+)
+
+// SerialTransport opens the port its selector picks. Without a selector that is
+// the first serial port the system reports, and the same port again on later
+// opens while it is still there.
+type SerialTransport struct {
+	selector PortSelector
+	port     string
+}
+
+func NewSerialTransport(selector PortSelector) *SerialTransport {
+	return &SerialTransport{selector: selector}
+}
+
+func (t *SerialTransport) Name() string {
+	return TransportSerial
+}
+
+func (t *SerialTransport) Port() string {
+	return t.port
+}
+
+func (t *SerialTransport) Open(settings Settings) (*TTY, error) {
+	path, err := t.find()
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := OpenTTY(path, settings)
+	if err != nil {
+		return nil, err
+	}
+	t.port = path
+
+	return port, nil
+}
+
+func (t *SerialTransport) find() (string, error) {
+	if t.selector != (PortSelector{}) || t.port == "" {
+		return t.selector.Find()
+	}
+	ports, err := serial.GetPortsList()
+	if err != nil {
+		return "", fmt.Errorf("failed to list ports: %w", err)
+	}
+	if slices.Contains(ports, t.port) {
+		return t.port, nil
+	}
+	return t.selector.Find()
+}
@@ -0,0 +1,147 @@
+package uart
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// TTY is a serial port the UART opened itself, so that all of its settings,
+// flow control included, go through termios on a descriptor it owns
+type TTY struct {
+	fd          int
+	readTimeout time.Duration
+	// settings are those termios reported after opening
+	settings Settings
+}
+
+// OpenTTY opens the tty at path exclusively and in raw mode with settings
+func OpenTTY(path string, settings Settings) (*TTY, error) {
+	// Without O_NONBLOCK the open waits for a carrier on some adapters
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open port %s: %w", path, err)
+	}
+	tty := &TTY{fd: fd}
+	if err := tty.open(settings); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to set up port %s: %w", path, err)
+	}
+	return tty, nil
+}
+
+func (t *TTY) open(settings Settings) error {
+	if err := unix.IoctlSetInt(t.fd, unix.TIOCEXCL, 0); err != nil {
+		return err
+	}
+	if err := unix.SetNonblock(t.fd, false); err != nil {
+		return err
+	}
+
+	termios, err := unix.IoctlGetTermios(t.fd, unix.TCGETS2)
+	if err != nil {
+		return err
+	}
+	setTermios(termios, settings)
+	if err := unix.IoctlSetTermios(t.fd, unix.TCSETS2, termios); err != nil {
+		return err
+	}
+	// Some ttys ignore parts of the settings, e.g. a pty keeps 8 data bits and no
+	// parity whatever was asked, so report what it really has
+	if termios, err = unix.IoctlGetTermios(t.fd, unix.TCGETS2); err != nil {
+		return err
+	}
+	t.settings = termiosSettings(settings.Baud, termios)
+	return nil
+}
+
+// setTermios puts termios into raw mode with settings, like cfmakeraw
+func setTermios(termios *unix.Termios, settings Settings) {
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR |
+		unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY | unix.INPCK
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CMSPAR | unix.CSTOPB | unix.CRTSCTS |
+		unix.CBAUD | unix.CIBAUD
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	// BOTHER takes the baud rate as it is instead of one of the Bxxx constants
+	termios.Cflag |= unix.CREAD | unix.CLOCAL | unix.BOTHER | dataBits[settings.DataBits] |
+		parities[settings.Parity] | stopBits[settings.StopBits]
+	termios.Ispeed = uint32(settings.Baud)
+	termios.Ospeed = uint32(settings.Baud)
+	if settings.Parity != "none" {
+		termios.Iflag |= unix.INPCK
+	}
+	switch settings.FlowControl {
+	case FlowRTSCTS:
+		termios.Cflag |= unix.CRTSCTS
+	case FlowXONXOFF:
+		termios.Iflag |= unix.IXON | unix.IXOFF
+	}
+}
+
+// Settings returns the settings the tty really has
+func (t *TTY) Settings() Settings {
+	return t.settings
+}
+
+// SetReadTimeout sets how long Read waits for data, 0 waits forever
+func (t *TTY) SetReadTimeout(timeout time.Duration) {
+	t.readTimeout = timeout
+}
+
+// Read returns 0 bytes without an error when nothing arrived within the read timeout
+func (t *TTY) Read(buffer []byte) (int, error) {
+	timeout := -1
+	if t.readTimeout > 0 {
+		timeout = int(t.readTimeout.Milliseconds())
+	}
+	fds := []unix.PollFd{{Fd: int32(t.fd), Events: unix.POLLIN}}
+	for {
+		n, err := unix.Poll(fds, timeout)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, nil
+		}
+		break
+	}
+
+	for {
+		n, err := unix.Read(t.fd, buffer)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+}
+
+func (t *TTY) Write(data []byte) (int, error) {
+	written := 0
+	for written < len(data) {
+		n, err := unix.Write(t.fd, data[written:])
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func (t *TTY) Close() error {
+	return unix.Close(t.fd)
+}
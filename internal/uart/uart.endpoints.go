@@ -2,6 +2,7 @@ package uart
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -22,6 +23,13 @@ func HandleUartChangeSpeed(u *UART) func(c *gin.Context) {
 			return
 		}
 
+		settings := u.Settings()
+		settings.Baud = req.Speed
+		if err := settings.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		err := u.ChangeSpeed(req.Speed)
 		if err != nil {
 			fmt.Println("error changing speed: ", err)
@@ -32,4 +40,52 @@ func HandleUartChangeSpeed(u *UART) func(c *gin.Context) {
 	}
 }
 
+// HandleGetUartConfig returns the serial line settings in effect
+func HandleGetUartConfig(u *UART) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, u.Settings())
+	}
+}
+
+// HandlePutUartConfig reopens the port with new settings. Fields left out of the
+// body keep their current value. It returns the settings actually applied.
+func HandlePutUartConfig(u *UART) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		settings := u.Settings()
+		if err := json.NewDecoder(c.Request.Body).Decode(&settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		if err := settings.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
+		if err := u.Configure(settings); err != nil {
+			fmt.Println("error configuring UART: ", err)
+			if errors.Is(err, ErrNotOpen) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to configure the UART: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, u.Settings())
+	}
+}
+
+// HandleListPorts lists the serial ports the UART could use, with their USB
+// details, and the one it has open
+func HandleListPorts(u *UART) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ports, err := ListPorts()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if ports == nil {
+			ports = []PortInfo{}
+		}
+		c.JSON(http.StatusOK, gin.H{"ports": ports, "current": u.Info().Port})
+	}
+}
@@ -1,132 +1,233 @@
 package uart
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
-
-	"go.bug.st/serial"
 )
 
 type UART struct {
-    port     serial.Port
-    mu       sync.Mutex
-    isActive bool
+	transport Transport
+	port      *TTY
+	mu        sync.Mutex
+	isActive  bool
+	// settings are those the port was opened with last
+	settings Settings
+	// defaultSpeed is used whenever the port is opened
+	defaultSpeed int
 }
 
-func NewUART() *UART {
-    return &UART{}
+// ErrNotOpen is returned when changing the settings of a closed port
+var ErrNotOpen = errors.New("the UART is not open")
+
+// Info describes the UART link for clients
+type Info struct {
+	Transport string    `json:"transport"`
+	Port      string    `json:"port"`
+	Speed     int       `json:"speed"`
+	Active    bool      `json:"active"`
+	Settings  *Settings `json:"settings,omitempty"`
 }
 
-func openSerialPort(baudRate int) (serial.Port, error) {
-	mode := &serial.Mode{
-		BaudRate: baudRate,
-	}
+func NewUART(transport Transport, defaultSpeed int) *UART {
+	return &UART{transport: transport, defaultSpeed: defaultSpeed}
+}
+
+// SetDefaultSpeed changes the speed used the next time the port is opened
+func (u *UART) SetDefaultSpeed(speed int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.defaultSpeed = speed
+}
 
-	ports, err := serial.GetPortsList()
+// openSerialPort returns the port and the settings it really got
+func (u *UART) openSerialPort(settings Settings) (*TTY, Settings, error) {
+	port, err := u.transport.Open(settings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list ports: %w", err)
+		return nil, settings, err
 	}
+	return port, port.Settings(), nil
+}
 
-	if len(ports) == 0 {
-		return nil, fmt.Errorf("no serial ports found")
+// Open opens the port with the default settings
+func (u *UART) Open() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.open(DefaultSettings(u.defaultSpeed))
+}
+
+func (u *UART) open(settings Settings) error {
+	if u.isActive {
+		return nil
 	}
 
-	port, err := serial.Open(ports[0], mode)
+	port, applied, err := u.openSerialPort(settings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open port %s: %w", ports[0], err)
+		return err
 	}
 
-	return port, nil
+	u.port = port
+	u.isActive = true
+	u.settings = applied
+	return nil
 }
 
-func (u *UART) Open() error {
-    u.mu.Lock()
-    defer u.mu.Unlock()
-
-    if u.isActive {
-        return nil
-    }
-
-    port, err := openSerialPort(9600)
-    if err != nil {
-        return err
-    }
+func (u *UART) Info() Info {
+	u.mu.Lock()
+	defer u.mu.Unlock()
 
-    u.port = port
-    u.isActive = true
-    return nil
+	info := Info{Transport: u.transport.Name(), Port: u.transport.Port(), Active: u.isActive}
+	if u.isActive {
+		settings := u.settings
+		info.Speed = settings.Baud
+		info.Settings = &settings
+	}
+	return info
 }
 
 func (u *UART) Close() error {
-    u.mu.Lock()
-    defer u.mu.Unlock()
+	u.mu.Lock()
+	defer u.mu.Unlock()
 
-    if !u.isActive {
-        return nil
-    }
+	if !u.isActive {
+		return nil
+	}
 
-    err := u.port.Close()
-    if err != nil {
-        return err
-    }
+	err := u.port.Close()
+	if err != nil {
+		return err
+	}
 
-    u.port = nil
-    u.isActive = false
-    return nil
+	u.port = nil
+	u.isActive = false
+	return nil
 }
 
+// Reset reopens the port with the settings it had last, e.g. after flashing
 func (u *UART) Reset() error {
-    if err := u.Close(); err != nil {
-        return err
-    }
-    return u.Open()
+	if err := u.Close(); err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	settings := u.settings
+	if settings.Baud == 0 {
+		settings = DefaultSettings(u.defaultSpeed)
+	}
+	return u.open(settings)
 }
 
 func (u *UART) Read(buffer []byte) (int, error) {
-    u.mu.Lock()
-    defer u.mu.Unlock()
+	u.mu.Lock()
+	defer u.mu.Unlock()
 
-    
-    if !u.isActive {
-        return 0, nil
-    }
-    u.port.SetReadTimeout(time.Millisecond * 100)
-    return u.port.Read(buffer)
+	if !u.isActive {
+		return 0, nil
+	}
+	u.port.SetReadTimeout(time.Millisecond * 100)
+	return u.port.Read(buffer)
 }
 
 func (u *UART) Write(data []byte) error {
-    u.mu.Lock()
-    defer u.mu.Unlock()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.isActive {
+		return nil
+	}
+	_, err := u.port.Write(data)
+	fmt.Println("Data written to UART: ", string(data))
+	return err
+}
 
-    if !u.isActive {
-        return nil
-    }
-    _, err := u.port.Write(data)
-    fmt.Println("Data written to UART: ", string(data))
-    return err
+// Settings returns the settings of the open port, or those it will be opened
+// with while it is closed
+func (u *UART) Settings() Settings {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.isActive {
+		return DefaultSettings(u.defaultSpeed)
+	}
+	return u.settings
 }
+
+// RestoreDefaults switches the port back to the settings it is opened with
+func (u *UART) RestoreDefaults() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.isActive {
+		return nil
+	}
+	return u.reopen(DefaultSettings(u.defaultSpeed))
+}
+
+// ChangeSpeed reopens the port at speed with its other settings unchanged. The
+// new settings are checked like those of Configure.
 func (u *UART) ChangeSpeed(speed int) error {
 	u.mu.Lock()
 	defer u.mu.Unlock()
-	
-    fmt.Println("Changing speed to", speed)
 
+	fmt.Println("Changing speed to", speed)
+
+	settings := u.settings
 	if !u.isActive {
-        fmt.Println("UART is not active, doing nothing...")
-        return nil
-	}
-
-    fmt.Println("Closing port for speed change")
-    u.port.Close()
-    fmt.Println("Opening port for speed change")
-    port, err := openSerialPort(speed)
-    if err != nil {
-        fmt.Println("Error opening port: ", err)
-        return err
-    }
-    u.port = port
-    fmt.Println("The port is opened")
-    fmt.Println("Speed changed to", speed)
+		settings = DefaultSettings(u.defaultSpeed)
+	}
+	settings.Baud = speed
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	if !u.isActive {
+		fmt.Println("UART is not active, doing nothing...")
+		return nil
+	}
+
+	if err := u.reopen(settings); err != nil {
+		return err
+	}
+	fmt.Println("Speed changed to", speed)
+	return nil
+}
+
+// Configure reopens the port with settings
+func (u *UART) Configure(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.isActive {
+		return ErrNotOpen
+	}
+	return u.reopen(settings)
+}
+
+// reopen must be called with u.mu held and the port open. If the port can't be
+// opened with settings it goes back to the previous ones.
+func (u *UART) reopen(settings Settings) error {
+	fmt.Println("Closing port for settings change")
+	u.port.Close()
+	fmt.Println("Opening port with", settings)
+	port, applied, err := u.openSerialPort(settings)
+	if err != nil {
+		fmt.Println("Error opening port: ", err)
+		port, applied, err = u.openSerialPort(u.settings)
+		if err != nil {
+			fmt.Println("Error reopening port with the previous settings: ", err)
+			u.port = nil
+			u.isActive = false
+			return err
+		}
+		u.port = port
+		u.settings = applied
+		return fmt.Errorf("failed to apply %+v, kept the previous settings", settings)
+	}
+	u.port = port
+	u.settings = applied
+	fmt.Println("The port is opened")
 	return nil
 }
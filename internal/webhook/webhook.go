@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"digitrans-lab-go/internal/config"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Events sent to the master server
+const (
+	SessionStarted      = "session-started"
+	SessionOverwritten  = "session-overwritten"
+	SessionExpired      = "session-expired"
+	SessionDeleted      = "session-deleted"
+	SessionCancelled    = "session-cancelled"
+	SessionReleased     = "session-released"
+	WebSocketConnected  = "websocket-connected"
+	WebSocketDropped    = "websocket-disconnected"
+	FirmwareFlashed     = "firmware-flashed"
+	PeripheralLost      = "peripheral-lost"
+	PeripheralRecovered = "peripheral-recovered"
+	StationReset        = "station-reset"
+)
+
+// maxPending bounds the outbox while the master server is unreachable. The
+// oldest events are dropped first.
+const maxPending = 1000
+
+// firstRetry is the wait after the first failed delivery, it doubles up to master_server.retry_max
+const firstRetry = time.Second
+
+// Event is what the master server receives, one per request
+type Event struct {
+	// ID is the same on every delivery attempt, so the master server can drop duplicates
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Station string          `json:"station"`
+	Time    time.Time       `json:"time"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// errRejected means the master server refused the event for good, retrying won't help
+var errRejected = errors.New("rejected by the master server")
+
+// Emitter delivers events to master_server.url in order. Events wait in an
+// outbox until the master server accepts them, and failed deliveries are
+// retried with exponential backoff.
+type Emitter struct {
+	cfg    *config.Live
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []Event
+	// onChange runs after the outbox changed, so it can be saved
+	onChange func()
+	wake     chan struct{}
+}
+
+// NewEmitter creates an emitter whose outbox starts with the events that were
+// still pending before a restart
+func NewEmitter(cfg *config.Live, pending []Event) *Emitter {
+	return &Emitter{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		pending: slices.Clone(pending),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// SetOnChange registers fn to run whenever the outbox changes. It runs without
+// the emitter locked, so fn may call Pending.
+func (e *Emitter) SetOnChange(fn func()) {
+	e.onChange = fn
+}
+
+func (e *Emitter) changed() {
+	if e.onChange != nil {
+		e.onChange()
+	}
+}
+
+// Pending returns the events that haven't been delivered yet, oldest first
+func (e *Emitter) Pending() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.pending)
+}
+
+// Emit queues an event. data is encoded as JSON. Nothing is queued while no
+// master server URL is configured.
+func (e *Emitter) Emit(eventType string, data any) {
+	cfg := e.cfg.Get()
+	if cfg.MasterServer.URL == "" {
+		return
+	}
+
+	event := Event{ID: newEventID(), Type: eventType, Station: cfg.Station.ID, Time: time.Now()}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Error encoding %s event: %v", eventType, err)
+			return
+		}
+		event.Data = encoded
+	}
+
+	e.mu.Lock()
+	e.pending = append(e.pending, event)
+	if dropped := len(e.pending) - maxPending; dropped > 0 {
+		log.Printf("Webhook outbox is full, dropping %d old events", dropped)
+		e.pending = slices.Delete(e.pending, 0, dropped)
+	}
+	e.mu.Unlock()
+	e.changed()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers the outbox until ctx is done
+func (e *Emitter) Run(ctx context.Context) {
+	var backoff time.Duration
+	for {
+		event, ok := e.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.wake:
+				continue
+			}
+		}
+
+		err := e.deliver(ctx, event)
+		if err == nil || errors.Is(err, errRejected) {
+			if err != nil {
+				log.Printf("Dropping %s event %s: %v", event.Type, event.ID, err)
+			}
+			e.remove(event.ID)
+			backoff = 0
+			continue
+		}
+
+		backoff = min(max(2*backoff, firstRetry), e.cfg.Get().MasterServer.RetryMax)
+		log.Printf("Error sending %s event, retrying in %s: %v", event.Type, backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (e *Emitter) next() (Event, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.pending) == 0 {
+		return Event{}, false
+	}
+	return e.pending[0], true
+}
+
+func (e *Emitter) remove(id string) {
+	e.mu.Lock()
+	e.pending = slices.DeleteFunc(e.pending, func(event Event) bool { return event.ID == id })
+	e.mu.Unlock()
+	e.changed()
+}
+
+func (e *Emitter) deliver(ctx context.Context, event Event) error {
+	cfg := e.cfg.Get()
+	if cfg.MasterServer.URL == "" {
+		return errors.New("master_server.url is not set")
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.MasterServer.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Station-Event", event.Type)
+	request.Header.Set("X-Station-Timestamp", timestamp)
+	request.Header.Set("X-Station-Signature", "sha256="+Sign(signingKey(cfg), timestamp, body))
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	switch {
+	case response.StatusCode < http.StatusMultipleChoices:
+		return nil
+	case response.StatusCode == http.StatusRequestTimeout, response.StatusCode == http.StatusTooManyRequests,
+		response.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("master server answered %s", response.Status)
+	default:
+		return fmt.Errorf("%w: %s", errRejected, response.Status)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp + "." + body, which the master
+// server recomputes to check that an event came from this station
+func Sign(key []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func signingKey(cfg *config.Config) []byte {
+	if cfg.MasterServer.WebhookSecret != "" {
+		return []byte(cfg.MasterServer.WebhookSecret)
+	}
+	return []byte(cfg.Auth.MasterServerAPISecret)
+}
+
+func newEventID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"context"
+	"digitrans-lab-go/internal/config"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type received struct {
+	event     Event
+	signature string
+	timestamp string
+	body      []byte
+}
+
+// masterServer answers deliveries with statuses in turn, then with 200
+type masterServer struct {
+	mu       sync.Mutex
+	statuses []int
+	received []received
+	done     chan struct{}
+	want     int
+}
+
+func (m *masterServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var event Event
+	json.Unmarshal(body, &event)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.received = append(m.received, received{
+		event:     event,
+		signature: r.Header.Get("X-Station-Signature"),
+		timestamp: r.Header.Get("X-Station-Timestamp"),
+		body:      body,
+	})
+	status := http.StatusOK
+	if len(m.statuses) > 0 {
+		status, m.statuses = m.statuses[0], m.statuses[1:]
+	}
+	w.WriteHeader(status)
+	if len(m.received) == m.want {
+		close(m.done)
+	}
+}
+
+func TestEmitterRetriesSignedEvents(t *testing.T) {
+	master := &masterServer{
+		// The first event fails twice before it goes through, the second is refused for good
+		statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK, http.StatusBadRequest},
+		done:     make(chan struct{}),
+		want:     5,
+	}
+	server := httptest.NewServer(master)
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Station.ID = "lab-1"
+	cfg.MasterServer.URL = server.URL
+	cfg.MasterServer.WebhookSecret = "webhook-key"
+	cfg.MasterServer.RetryMax = 10 * time.Millisecond
+	emitter := NewEmitter(config.NewLive("", &cfg), nil)
+
+	emitter.Emit(SessionStarted, map[string]string{"session": "s1"})
+	emitter.Emit(SessionExpired, map[string]string{"session": "s1"})
+	emitter.Emit(SessionDeleted, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go emitter.Run(ctx)
+
+	select {
+	case <-master.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the master server didn't get every delivery")
+	}
+
+	master.mu.Lock()
+	defer master.mu.Unlock()
+	wantTypes := []string{SessionStarted, SessionStarted, SessionStarted, SessionExpired, SessionDeleted}
+	for i, got := range master.received {
+		if got.event.Type != wantTypes[i] {
+			t.Errorf("delivery %d is %s, want %s", i, got.event.Type, wantTypes[i])
+		}
+		if got.event.Station != "lab-1" {
+			t.Errorf("delivery %d is from station %q", i, got.event.Station)
+		}
+		if want := "sha256=" + Sign([]byte("webhook-key"), got.timestamp, got.body); got.signature != want {
+			t.Errorf("delivery %d is signed %q, want %q", i, got.signature, want)
+		}
+	}
+	// Retries repeat the event, so the master server can drop duplicates
+	if id := master.received[0].event.ID; master.received[1].event.ID != id || master.received[2].event.ID != id {
+		t.Errorf("retries changed the event ID")
+	}
+	if master.received[2].event.ID == master.received[3].event.ID {
+		t.Errorf("two events share an ID")
+	}
+
+	// The outbox is emptied right after the last delivery is answered
+	for deadline := time.Now().Add(time.Second); len(emitter.Pending()) > 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pending := emitter.Pending(); len(pending) != 0 {
+		t.Errorf("events still pending: %v", pending)
+	}
+}
+
+func TestEmitterWithoutURLQueuesNothing(t *testing.T) {
+	cfg := config.Default()
+	emitter := NewEmitter(config.NewLive("", &cfg), nil)
+
+	emitter.Emit(SessionStarted, nil)
+	if pending := emitter.Pending(); len(pending) != 0 {
+		t.Fatalf("queued %v without a master server", pending)
+	}
+}
@@ -1,24 +1,40 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	analogdiscovery "digitrans-lab-go/internal/analog-discovery"
+	"digitrans-lab-go/internal/audit"
 	"digitrans-lab-go/internal/camera"
 	"digitrans-lab-go/internal/config"
 	currentsession "digitrans-lab-go/internal/current-session"
+	"digitrans-lab-go/internal/flasher"
 	"digitrans-lab-go/internal/fpga"
+	"digitrans-lab-go/internal/gpio"
 	"digitrans-lab-go/internal/multiplexer"
 	pcbswitch "digitrans-lab-go/internal/pcb-switch"
+	"digitrans-lab-go/internal/peripherals"
 	"digitrans-lab-go/internal/potentiometer"
+	stationstate "digitrans-lab-go/internal/station-state"
 	stm32flash "digitrans-lab-go/internal/stm32-flash"
 	"digitrans-lab-go/internal/timer"
 	"digitrans-lab-go/internal/uart"
+	"digitrans-lab-go/internal/webhook"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"net/http"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,192 +42,626 @@ import (
 )
 
 type Server struct {
+	cfg        *config.Live
 	u          *uart.UART
+	uartBuffer *uart.Buffer
 	wsUpgrader websocket.Upgrader
 	wsConn     *websocket.Conn
 	wsConnMu   sync.Mutex
-	timer      *timer.Timer
-	deviceType string
+	wsClients  sync.Map      // *websocket.Conn to *wsClient
+	wsMsgSeq   atomic.Uint64 // numbers the messages sent in protocol version 2
+	observers  map[*websocket.Conn]struct{}
+	audit      *audit.Log
+	events     *webhook.Emitter
+	idle       *currentsession.IdleMonitor
+	timer      *timer.Timer // the reconnect window after the student disconnected
+	expiry     *timer.Timer // ends the session at its end time
+	board      *peripherals.Peripheral[string]
+	mcu        flasher.Flasher
+	fpga       flasher.Flasher
+	// the instruments the WebSocket requests use
+	device *peripherals.Peripheral[*analogdiscovery.AnalogDiscoveryDevice]
+	pot    *peripherals.Peripheral[*potentiometer.Potentiometer]
+	mux    *peripherals.Peripheral[*multiplexer.MultiplexerModule]
 }
 
-func NewServer() *Server {
-	u := uart.NewUART()
-	u.Open()
+func NewServer(cfg *config.Live, transport uart.Transport, mcu, fpga flasher.Flasher, auditLog *audit.Log, events *webhook.Emitter) *Server {
+	u := uart.NewUART(transport, cfg.Get().UART.DefaultSpeed)
+	if err := u.Open(); err != nil {
+		log.Printf("Error opening UART on %s: %v", transport.Name(), err)
+	}
 	return &Server{
-		u: u,
+		cfg:        cfg,
+		u:          u,
+		uartBuffer: uart.NewBuffer(cfg.Get().UART.BufferSize),
 		wsUpgrader: websocket.Upgrader{
+			Subprotocols: []string{wsProtocolV2},
 			CheckOrigin: func(r *http.Request) bool {
 				return true
 			},
 		},
-		wsConn: nil,
-		timer:  timer.NewTimer(10*time.Second, func() {}),
+		wsConn:    nil,
+		observers: map[*websocket.Conn]struct{}{},
+		audit:     auditLog,
+		events:    events,
+		timer:     timer.NewTimer(10*time.Second, func() {}),
+		expiry:    timer.NewTimer(0, func() {}),
+		mcu:       mcu,
+		fpga:      fpga,
 	}
 }
 
-func findWorkingCamera() (string, error) {
+func findWorkingCamera(width, height int) (string, error) {
+	var errs []error
 	for i := 0; i <= 10; i++ {
 		device := fmt.Sprintf("/dev/video%d", i)
 
-		// Try to create a camera with this device
-		cam, err := camera.NewWebcamServer(device)
+		// Try to open a camera with this device. Failures are only reported together,
+		// as this runs again on every background retry while no camera is attached.
+		source, err := camera.NewV4L2Source(device, width, height)
 		if err != nil {
-			log.Printf("Camera device %s not working: %v", device, err)
+			errs = append(errs, fmt.Errorf("%s: %w", device, err))
 			continue
 		}
 
 		// If successful, close it and return the device path
-		cam.Close()
+		source.Close()
 		log.Printf("Found working camera device: %s", device)
 		return device, nil
 	}
 
-	return "", fmt.Errorf("no working camera device found in /dev/video0 through /dev/video10")
+	return "", fmt.Errorf("no working camera device found in /dev/video0 through /dev/video10: %w", errors.Join(errs...))
+}
+
+func newFrameSource(cfg *config.Config) (camera.FrameSource, error) {
+	settings := cfg.Camera
+	switch settings.Source {
+	case "v4l2":
+		if settings.Device != "" {
+			return camera.NewV4L2Source(settings.Device, settings.Width, settings.Height)
+		}
+		// Find the first working camera device
+		cameraDevice, err := findWorkingCamera(settings.Width, settings.Height)
+		if err != nil {
+			return nil, err
+		}
+		return camera.NewV4L2Source(cameraDevice, settings.Width, settings.Height)
+	case "file":
+		return camera.NewFileSource(settings.File, settings.FPS)
+	case "test-pattern":
+		return camera.NewTestPatternSource(settings.Width, settings.Height, settings.FPS), nil
+	default:
+		return nil, fmt.Errorf("unknown camera source: %s", settings.Source)
+	}
+}
+
+func newUARTTransport(settings config.UARTConfig) (uart.Transport, error) {
+	switch settings.Transport {
+	case uart.TransportSerial:
+		selector := uart.PortSelector{Path: settings.Port, VID: settings.VID, PID: settings.PID, SerialNumber: settings.SerialNumber}
+		return uart.NewSerialTransport(selector), nil
+	case uart.TransportPTY:
+		pty, err := uart.NewPTYTransport()
+		if err != nil {
+			return nil, err
+		}
+		// Nothing is wired to the other end, so let it behave like a loopback cable
+		go pty.Serve(context.Background(), uart.Echo)
+		log.Printf("UART is using pseudo-terminal %s with an echo peer", pty.SlavePath())
+		return pty, nil
+	default:
+		return nil, fmt.Errorf("unknown UART transport: %s", settings.Transport)
+	}
 }
 
 func main() {
 	r := gin.Default()
 
-	server := NewServer()
-
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Error loading config:", err)
+		log.Fatalf("Error loading config: %v", err)
 	}
 
-	// Find the first working camera device
-	cameraDevice, err := findWorkingCamera()
+	live := config.NewLive(config.Path(), cfg)
+
+	// Bring back the session from before a restart. One that ended while the
+	// backend was down stays ended and its board is powered off.
+	store, err := stationstate.Open(cfg.Session.StateFile)
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Starting without the saved state: %v", err)
 	}
+	saved := store.State()
 
-	cam, err := camera.NewWebcamServer(cameraDevice)
-	if err != nil {
-		log.Fatal(err)
+	// Events for the master server wait in the state file until they were delivered
+	events := webhook.NewEmitter(live, saved.Outbox)
+	events.SetOnChange(func() {
+		pending := events.Pending()
+		saveState(store, func(state *stationstate.State) { state.Outbox = pending })
+	})
+	go events.Run(context.Background())
+
+	session := currentsession.GetCurrentSession()
+	if saved.Session != nil {
+		if time.Now().Before(saved.Session.SessionEndTime) {
+			log.Printf("Restoring the session running until %s", saved.Session.SessionEndTime.Format(time.RFC3339))
+			session.Restore(*saved.Session)
+		} else {
+			log.Printf("The session saved before the restart ended at %s", saved.Session.SessionEndTime.Format(time.RFC3339))
+		}
 	}
-	defer cam.Close()
 
-	device, err := analogdiscovery.CreateDevice()
+	transport, err := newUARTTransport(cfg.UART)
 	if err != nil {
-		log.Fatalf("Error creating Analog Discovery device: %v", err)
+		log.Fatalf("Error creating UART transport: %v", err)
 	}
 
-	for _, outputPin := range analogdiscovery.OutputPins {
-		device.SetPinMode(outputPin, true)
+	runner := flasher.NewExecRunner()
+	stFlash := stm32flash.NewSTFlash(runner, cfg.MCU)
+	fpgaFlasher := fpga.CreateFPGA(runner, cfg.FPGA)
+	auditLog := audit.NewLog(func() string { return live.Get().Audit.Dir })
+	server := NewServer(live, transport, stFlash, fpgaFlasher, auditLog, events)
+	go server.pumpUART(context.Background())
+	analogdiscovery.SetAllowed(allowedFromConfig(cfg))
+
+	// Every instrument is optional: a missing one only disables its own routes and
+	// is retried in the background until it shows up. Open functions read the live
+	// config, so reopening one after a reload picks up its new settings.
+	gpioChip := peripherals.New("gpio", func() (gpio.Chip, error) {
+		cfg := live.Get()
+		return gpio.Open(cfg.GPIO.Backend, cfg.GPIO.Chip)
+	})
+
+	mux := peripherals.New("multiplexer", func() (*multiplexer.MultiplexerModule, error) {
+		chip, ok := gpioChip.Get()
+		if !ok {
+			return nil, fmt.Errorf("GPIO chip is unavailable")
+		}
+		cfg := live.Get()
+		return multiplexer.NewMultiplexerModule(chip,
+			cfg.Multiplexer.Mux1Pins[0], cfg.Multiplexer.Mux1Pins[1],
+			cfg.Multiplexer.Mux2Pins[0], cfg.Multiplexer.Mux2Pins[1])
+	}).WithClose((*multiplexer.MultiplexerModule).Close).WithProbe(func(mux *multiplexer.MultiplexerModule) error {
+		_, err := mux.InputChannel(1)
+		return err
+	})
+
+	switcher := peripherals.New("pcb-switch", func() (*pcbswitch.PCBSwitch, error) {
+		chip, ok := gpioChip.Get()
+		if !ok {
+			return nil, fmt.Errorf("GPIO chip is unavailable")
+		}
+		// A session restored after a restart, or running while the switch is
+		// reopened, keeps its board running
+		return pcbswitch.NewPCBSwitch(chip, live.Get().GPIO.PowerOnPin, currentsession.GetCurrentSession().IsActive())
+	}).WithClose((*pcbswitch.PCBSwitch).Close).WithProbe(func(switcher *pcbswitch.PCBSwitch) error {
+		_, err := switcher.IsPoweredOn()
+		return err
+	})
+
+	cam := peripherals.New("camera", func() (*camera.WebcamServer, error) {
+		frameSource, err := newFrameSource(live.Get())
+		if err != nil {
+			return nil, err
+		}
+		return camera.NewWebcamServer(frameSource), nil
+	}).WithClose(func(cam *camera.WebcamServer) error {
+		cam.Close()
+		return nil
+	})
+
+	var dwf analogdiscovery.Backend
+	device := peripherals.New("analog-discovery", func() (*analogdiscovery.AnalogDiscoveryDevice, error) {
+		if dwf == nil {
+			backend, err := analogdiscovery.NewBackend(cfg.AnalogDiscovery.Backend)
+			if err != nil {
+				return nil, fmt.Errorf("error loading Analog Discovery backend: %w", err)
+			}
+			dwf = backend
+		}
+		device, err := analogdiscovery.CreateDevice(dwf)
+		if err != nil {
+			return nil, err
+		}
+		for _, outputPin := range analogdiscovery.GetAllowed().OutputPins {
+			device.SetPinMode(outputPin, true)
+		}
+		return device, nil
+	}).WithClose(func(device *analogdiscovery.AnalogDiscoveryDevice) error {
+		device.Close()
+		return nil
+	}).WithProbe((*analogdiscovery.AnalogDiscoveryDevice).Probe)
+
+	pot := peripherals.New("potentiometer", func() (*potentiometer.Potentiometer, error) {
+		cfg := live.Get()
+		address := cfg.Potentiometer.Address
+		if cfg.Potentiometer.Backend == "emulated" {
+			return potentiometer.NewPotentiometer(potentiometer.NewEmulatedMAX5395(address), address)
+		}
+		bus, err := potentiometer.OpenBus(cfg.Potentiometer.Bus)
+		if err != nil {
+			return nil, fmt.Errorf("error opening I2C bus: %w", err)
+		}
+		pot, err := potentiometer.NewPotentiometer(bus, address)
+		if err != nil {
+			bus.Close()
+			return nil, err
+		}
+		return pot, nil
+	}).WithClose((*potentiometer.Potentiometer).Close).WithProbe((*potentiometer.Potentiometer).Probe)
+
+	server.board = peripherals.New("board", func() (string, error) {
+		// Flashing the example firmware would wipe the student's, so a restored
+		// session keeps the board that was detected before, if it still answers
+		if deviceType := store.State().DeviceType; deviceType != "" && currentsession.GetCurrentSession().IsActive() {
+			if err := server.flasher(deviceType).Probe(); err == nil {
+				return deviceType, nil
+			}
+		}
+		deviceType, err := server.CheckDeviceType()
+		if err == nil {
+			saveState(store, func(state *stationstate.State) { state.DeviceType = deviceType })
+		}
+		return deviceType, err
+	}).WithProbe(func(deviceType string) error {
+		// The programmer can't probe and flash at once, and students only
+		// flash during their session
+		if currentsession.GetCurrentSession().IsActive() {
+			return nil
+		}
+		return server.flasher(deviceType).Probe()
+	})
+
+	server.device, server.pot, server.mux = device, pot, mux
+
+	registry := peripherals.NewRegistry()
+	registry.Add(gpioChip, mux, switcher, cam, device, pot, server.board)
+	registry.OpenAll()
+	registry.OnChange(func(status peripherals.Status) {
+		if status.Available {
+			events.Emit(webhook.PeripheralRecovered, status)
+		} else {
+			events.Emit(webhook.PeripheralLost, status)
+		}
+	})
+	registry.RetryInBackground(context.Background(), func() time.Duration {
+		return live.Get().Peripherals.RetryInterval
+	})
+
+	reloader := &configReloader{
+		live: live,
+		apply: func(cfg *config.Config) {
+			server.u.SetDefaultSpeed(cfg.UART.DefaultSpeed)
+			server.uartBuffer.SetLimit(cfg.UART.BufferSize)
+			stFlash.Configure(cfg.MCU)
+			fpgaFlasher.Configure(cfg.FPGA)
+			analogdiscovery.SetAllowed(allowedFromConfig(cfg))
+			if device, ok := device.Get(); ok {
+				for _, outputPin := range cfg.AnalogDiscovery.OutputPins {
+					device.SetPinMode(outputPin, true)
+				}
+			}
+		},
+		reopen: []reopenRule{
+			{"gpio.power_on_pin", switcher},
+			{"multiplexer.", mux},
+			{"potentiometer.", pot},
+			{"camera.", cam},
+		},
 	}
+	go reloader.reloadOnSignal()
 
-	pot, err := potentiometer.NewPotentiometer()
-	if err != nil {
-		log.Fatalf("Error creating Potentiometer: %v", err)
+	powerOff := func() {
+		if switcher, ok := switcher.Get(); ok {
+			if err := switcher.PowerOff(); err != nil {
+				log.Printf("Error powering the board off: %v", err)
+			}
+		}
 	}
 
-	mux := multiplexer.NewMultiplexerModule(cfg.MULTIPLEXER_A0_1, cfg.MULTIPLEXER_A0_2, cfg.MULTIPLEXER_A1_1, cfg.MULTIPLEXER_A1_2)
+	reset := &stationReset{cfg: live, events: events, steps: map[string]func() error{
+		"wavegen":       withPeripheral(device, (*analogdiscovery.AnalogDiscoveryDevice).StopWavegen),
+		"outputs":       withPeripheral(device, (*analogdiscovery.AnalogDiscoveryDevice).DriveOutputsLow),
+		"potentiometer": withPeripheral(pot, (*potentiometer.Potentiometer).Reset),
+		"multiplexer":   withPeripheral(mux, (*multiplexer.MultiplexerModule).Reset),
+		"uart":          server.u.RestoreDefaults,
+		"firmware": withPeripheral(server.board, func(deviceType string) error {
+			return server.flashFirmware(deviceType, server.exampleFirmware(deviceType), nil)
+		}),
+	}}
+
+	// endSession kicks the client out and leaves the station in a known state
+	endSession := func(reason string) {
+		server.timer.Stop()
+		server.expiry.Stop()
+		server.diconnectWebSocket(reason)
+		reset.Run(reason)
+		powerOff()
+	}
+	countdown := newSessionCountdown(live, server.notifyWebSocket)
+	go countdown.Run(context.Background())
+	// armExpiry ends the session at its end time. It only (re)starts the expiry
+	// timer, so extending a session leaves the reconnect window of a student who
+	// is disconnected running.
+	armExpiry := func() {
+		secondsRemaining := currentsession.GetCurrentSession().SessionEndTime().Sub(time.Now()).Seconds()
+		fmt.Println("Starting session timer for ", secondsRemaining, " seconds")
+		server.expiry.SetDuration(time.Duration(secondsRemaining) * time.Second)
+		server.expiry.Start(func() {
+			// A session the reconnect window already ended still gets the station reset
+			if session.IsActive() {
+				events.Emit(webhook.SessionExpired, gin.H{"session": session.ID()})
+			}
+			endSession(resetExpired)
+		})
+	}
+	// startSession powers the board up fresh for a new session
+	startSession := func() {
+		armExpiry()
+		countdown.Restart()
+		server.idle.Touch()
+		events.Emit(webhook.SessionStarted, gin.H{"session": session.ID(), "sessionEndTime": session.SessionEndTime()})
+		// The new student sees their board's output from power on, and nothing of the last session
+		server.uartBuffer.Clear()
+		if switcher, ok := switcher.Get(); ok {
+			if err := switcher.Reset(); err != nil {
+				log.Printf("Error power-cycling the board: %v", err)
+			}
+		}
+	}
+	// preemptSession makes room for the next session
+	preemptSession := func() {
+		events.Emit(webhook.SessionOverwritten, gin.H{"session": session.ID()})
+		server.diconnectWebSocket(resetOverwritten)
+		reset.Run(resetOverwritten)
+	}
+	schedule := currentsession.NewSchedule(preemptSession,
+		func(currentsession.Reservation) { startSession() },
+		func(r currentsession.Reservation) {
+			events.Emit(webhook.SessionCancelled, gin.H{"session": session.ID(), "reservation": r.ID})
+			endSession(resetCancelled)
+		})
+	tokens := currentsession.NewTokenValidator(live, schedule, startSession)
+	// Students who stopped using the station are warned, then make room for others
+	server.idle = currentsession.NewIdleMonitor(live, func(releaseAt time.Time) {
+		server.notifyWebSocket(WsMessage{Type: "idle-warning", Text: releaseAt.Format(time.RFC3339)})
+	}, func() {
+		log.Printf("Releasing idle session %s", session.ID())
+		released := gin.H{"session": session.ID(), "reason": resetIdle}
+		// The rest of a reserved slot is free for someone else
+		if r, ok := schedule.ReleaseActive(session.Token()); ok {
+			released["reservation"] = r.ID
+			released["freedUntil"] = r.EndTime
+		}
+		events.Emit(webhook.SessionReleased, released)
+		endSession(resetIdle)
+	})
+	go server.idle.Watch(context.Background())
 
-	switcher := pcbswitch.NewPCBSwitch(cfg.POWER_ON_PIN)
-	switcher.PowerOff()
+	session.SetOnChange(func() {
+		saveState(store, func(state *stationstate.State) { state.Session = session.State() })
+	})
+	schedule.SetOnChange(func() {
+		reservations, active := schedule.Snapshot()
+		saveState(store, func(state *stationstate.State) {
+			state.Reservations = reservations
+			state.ActiveReservation = active
+		})
+	})
+	if session.IsActive() {
+		armExpiry()
+		countdown.Restart()
+	} else if saved.Session != nil {
+		events.Emit(webhook.SessionExpired, gin.H{"session": saved.Session.ID})
+		reset.Run(resetExpired)
+		powerOff()
+		saveState(store, func(state *stationstate.State) { state.Session = nil })
+	}
+	// Only once the station is cleaned up, a reservation that is due right away
+	// mustn't be reset or powered off by the cleanup of the expired session
+	schedule.Restore(saved.Reservations, saved.ActiveReservation)
 
 	clientAuthQueryRoutes := r.Group("")
 	{
-		clientAuthQueryRoutes.Use(ClientAuthQueryMiddleware())
+		clientAuthQueryRoutes.Use(ClientAuthQueryMiddleware(tokens), server.trackActivity())
 
-		clientAuthQueryRoutes.Any("/api/stream", cam.ServeHTTP)
+		clientAuthQueryRoutes.Any("/api/stream", peripherals.Handle(cam, func(cam *camera.WebcamServer) func(c *gin.Context) {
+			return cam.ServeHTTP
+		}))
 		clientAuthQueryRoutes.GET("/ws", func(c *gin.Context) {
+			if c.GetString(roleKey) == currentsession.RoleObserver {
+				server.handleObserverWebSocket(c.Writer, c.Request)
+				return
+			}
 			server.handleWebSocket(c.Writer, c.Request)
 		})
 	}
 
 	clientAuthRoutes := r.Group("")
 	{
-		clientAuthRoutes.Use(ClientAuthMiddleware())
-
-		clientAuthRoutes.POST("/api/firmware/fpga", handleFirmware(*cfg, true, server))
-		clientAuthRoutes.POST("/api/firmware/mcu", handleFirmware(*cfg, false, server))
-		clientAuthRoutes.POST("/api/write-pin", analogdiscovery.HandleWritePin(device))
-		clientAuthRoutes.POST("/api/wavegen/write-channel", analogdiscovery.HandleWavegenEnableChannel(device))
-		clientAuthRoutes.POST("/api/wavegen/write-function", analogdiscovery.HandleWavegenFunctionSet(device))
-		clientAuthRoutes.POST("/api/wavegen/write-amplitude", analogdiscovery.HandleWavegenAmplitudeSet(device))
-		clientAuthRoutes.POST("/api/wavegen/write-frequency", analogdiscovery.HandleWavegenFrequencySet(device))
-		clientAuthRoutes.POST("/api/wavegen/write-duty-cycle", analogdiscovery.HandleWavegenDutyCycleSet(device))
-		clientAuthRoutes.POST("/api/scope/get-scope-data", analogdiscovery.HandleScopeGetData(device))
-		clientAuthRoutes.POST("/api/logic-analyzer/capture", analogdiscovery.HandleLogicAnalyzerCapture(device))
-		clientAuthRoutes.POST("/api/wavegen/write-config", analogdiscovery.HandleWavegenRun(device))
+		clientAuthRoutes.Use(ClientAuthMiddleware(tokens), server.trackActivity(), audit.Middleware(server.audit, func(c *gin.Context) (string, string) {
+			return currentsession.GetCurrentSession().ID(), c.GetString(roleKey)
+		}))
+
 		clientAuthRoutes.GET("/api/my-session", func(c *gin.Context) {
 			cs := currentsession.GetCurrentSession()
-			c.JSON(http.StatusOK, gin.H{"sessionEndTime": cs.SessionEndTime, "deviceType": server.deviceType})
+			deviceType, _ := server.board.Get()
+			c.JSON(http.StatusOK, gin.H{"sessionEndTime": cs.SessionEndTime(), "deviceType": deviceType, "role": c.GetString(roleKey)})
 		})
-		clientAuthRoutes.POST("/api/potentiometer/resistance", potentiometer.HandlePotentiometerSetResistancePercentage(pot))
-		clientAuthRoutes.GET("/api/potentiometer/resistance", potentiometer.HandlePotentiometerGetResistancePercentage(pot))
-		clientAuthRoutes.POST("/api/mcu/reset", stm32flash.HandleSTM32Reset(*cfg))
-		clientAuthRoutes.POST("/api/uart/speed", uart.HandleUartChangeSpeed(server.u))
-		clientAuthRoutes.POST("/api/multiplexer", multiplexer.HandleSelectInputChannel(mux))
-		clientAuthRoutes.GET("/api/multiplexer", multiplexer.HandleGetInputChannel(mux))
+		clientAuthRoutes.GET("/api/potentiometer/resistance", peripherals.Handle(pot, potentiometer.HandlePotentiometerGetResistancePercentage))
+		clientAuthRoutes.GET("/api/multiplexer", peripherals.Handle(mux, multiplexer.HandleGetInputChannel))
+		clientAuthRoutes.GET("/api/uart/config", uart.HandleGetUartConfig(server.u))
+	}
+
+	// Everything that changes the station is off limits for observers, who are
+	// told about each change instead
+	studentRoutes := clientAuthRoutes.Group("")
+	{
+		studentRoutes.Use(StudentOnlyMiddleware(), server.mirrorStateChanges())
+
+		studentRoutes.POST("/api/firmware/fpga", peripherals.Require(server.board), handleFirmware(server, deviceFPGA))
+		studentRoutes.POST("/api/firmware/mcu", peripherals.Require(server.board), handleFirmware(server, deviceMCU))
+		studentRoutes.POST("/api/write-pin", peripherals.Handle(device, analogdiscovery.HandleWritePin))
+		studentRoutes.POST("/api/wavegen/write-channel", peripherals.Handle(device, analogdiscovery.HandleWavegenEnableChannel))
+		studentRoutes.POST("/api/wavegen/write-function", peripherals.Handle(device, analogdiscovery.HandleWavegenFunctionSet))
+		studentRoutes.POST("/api/wavegen/write-amplitude", peripherals.Handle(device, analogdiscovery.HandleWavegenAmplitudeSet))
+		studentRoutes.POST("/api/wavegen/write-frequency", peripherals.Handle(device, analogdiscovery.HandleWavegenFrequencySet))
+		studentRoutes.POST("/api/wavegen/write-duty-cycle", peripherals.Handle(device, analogdiscovery.HandleWavegenDutyCycleSet))
+		studentRoutes.POST("/api/scope/get-scope-data", peripherals.Handle(device, analogdiscovery.HandleScopeGetData))
+		studentRoutes.POST("/api/logic-analyzer/capture", peripherals.Handle(device, analogdiscovery.HandleLogicAnalyzerCapture))
+		studentRoutes.POST("/api/wavegen/write-config", peripherals.Handle(device, analogdiscovery.HandleWavegenRun))
+		studentRoutes.POST("/api/potentiometer/resistance", peripherals.Handle(pot, potentiometer.HandlePotentiometerSetResistancePercentage))
+		studentRoutes.POST("/api/mcu/reset", peripherals.Require(server.board), stm32flash.HandleSTM32Reset(server.mcu))
+		studentRoutes.POST("/api/uart/speed", uart.HandleUartChangeSpeed(server.u))
+		studentRoutes.PUT("/api/uart/config", uart.HandlePutUartConfig(server.u))
+		studentRoutes.POST("/api/multiplexer", peripherals.Handle(mux, multiplexer.HandleSelectInputChannel))
+	}
+
+	anyAuthRoutes := r.Group("")
+	{
+		anyAuthRoutes.Use(ClientOrBackendAuthMiddleware(live, tokens))
+
+		anyAuthRoutes.GET("/api/capabilities", handleCapabilities(server, registry, cam, device, pot, mux))
 	}
 
 	backendAuthRoutes := r.Group("")
 	{
-		backendAuthRoutes.Use(BackendAuthMiddleware(*cfg))
-
-		backendAuthRoutes.POST("/api/session", currentsession.HandleCreateSession(*cfg, func() {
-			secondsRemaining := currentsession.GetCurrentSession().SessionEndTime.Sub(time.Now()).Seconds()
-			fmt.Println("Session created, starting timer for ", secondsRemaining, " seconds")
-			server.timer.SetDuration(time.Duration(secondsRemaining) * time.Second)
-			server.timer.Start(func() {
-				server.diconnectWebSocket()
-				switcher.PowerOff()
-			})
-			switcher.Reset()
-		}, func() {
-			server.diconnectWebSocket()
-		}))
+		backendAuthRoutes.Use(BackendAuthMiddleware(live))
+
+		backendAuthRoutes.POST("/api/session", currentsession.HandleCreateSession(*cfg, startSession, preemptSession))
 		backendAuthRoutes.GET("/api/session", currentsession.HandleGetSession(*cfg))
+		backendAuthRoutes.PATCH("/api/session", currentsession.HandleExtendSession(schedule, func() {
+			armExpiry()
+			countdown.Extended()
+		}))
+		backendAuthRoutes.POST("/api/admin/reload-config", handleReloadConfig(reloader))
 		backendAuthRoutes.DELETE("/api/session", currentsession.HandleDeleteSession(*cfg, func() {
-			server.timer.Stop()
-			server.diconnectWebSocket()
-			switcher.PowerOff()
+			events.Emit(webhook.SessionDeleted, gin.H{"session": session.ID()})
+			endSession(resetDeleted)
 		}))
+		backendAuthRoutes.GET("/api/station-reset", handleGetStationReset(reset))
+		backendAuthRoutes.GET("/api/uart/ports", uart.HandleListPorts(server.u))
+		backendAuthRoutes.GET("/api/audit", audit.HandleListLogs(server.audit))
+		backendAuthRoutes.GET("/api/audit/:session", audit.HandleDownloadLog(server.audit))
+
+		backendAuthRoutes.GET("/api/session/reservations", currentsession.HandleListReservations(schedule))
+		backendAuthRoutes.POST("/api/session/reservations", currentsession.HandleAddReservations(schedule))
+		backendAuthRoutes.PUT("/api/session/reservations/:id", currentsession.HandleRescheduleReservation(schedule))
+		backendAuthRoutes.DELETE("/api/session/reservations/:id", currentsession.HandleCancelReservation(schedule))
 	}
 
-	// Check which device is connected:
-	err = server.CheckDeviceType(cfg)
-
-	if err == nil {
-		log.Println("Found device: ", server.deviceType)
-		log.Fatal(r.Run(":" + cfg.PORT))
-	} else {
-		log.Fatal("Couldn't detect any device connected: ", err)
+	for _, status := range registry.Statuses() {
+		if !status.Available {
+			log.Printf("Starting without %s: %s", status.Name, status.Error)
+			events.Emit(webhook.PeripheralLost, status)
+		}
+	}
+	if deviceType, ok := server.board.Get(); ok {
+		log.Println("Found device: ", deviceType)
 	}
+	log.Fatal(r.Run(":" + strconv.Itoa(cfg.Server.Port)))
 }
 
-func (s *Server) CheckDeviceType(cfg *config.Config) error {
-	err := flashMCU(*cfg, filepath.Join("/", "home", "pi", "digitrans-lab-go", "example-firmware", "new-mcu-3.hex"), s)
-	if err == nil {
-		s.deviceType = "mcu"
-		return nil
+func saveState(store *stationstate.Store, update func(state *stationstate.State)) {
+	if err := store.Update(update); err != nil {
+		log.Printf("Error saving station state: %v", err)
 	}
-	fmt.Println("No new MCU found because of error: ", err)
+}
 
-	err = flashFPGA(*cfg, filepath.Join("/", "home", "pi", "digitrans-lab-go", "example-firmware", "fpga.svf"))
-	if err == nil {
-		s.deviceType = "fpga"
-		return nil
+const (
+	deviceMCU  = "mcu"
+	deviceFPGA = "fpga"
+)
+
+// CheckDeviceType probes each programmer and flashes the example firmware
+// onto the first target that answers
+func (s *Server) CheckDeviceType() (string, error) {
+	var errs []error
+	for _, deviceType := range []string{deviceMCU, deviceFPGA} {
+		if err := s.flasher(deviceType).Probe(); err != nil {
+			fmt.Println("No", deviceType, "found because of error:", err)
+			errs = append(errs, fmt.Errorf("%s: %w", deviceType, err))
+			continue
+		}
+		if err := s.flashFirmware(deviceType, s.exampleFirmware(deviceType), nil); err != nil {
+			fmt.Println("Flashing example firmware onto", deviceType, "failed:", err)
+			errs = append(errs, fmt.Errorf("%s: %w", deviceType, err))
+			continue
+		}
+		return deviceType, nil
 	}
 
-	return fmt.Errorf("No device detected: %w", err)
+	return "", fmt.Errorf("No device detected: %w", errors.Join(errs...))
+}
+
+// roleKey is where the client auth middlewares store the role of the client
+const roleKey = "role"
+
+func ClientAuthQueryMiddleware(tokens *currentsession.TokenValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := tokens.AuthorizeHttpQuery(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.Set(roleKey, role)
+		c.Next()
+	}
 }
 
-func ClientAuthQueryMiddleware() gin.HandlerFunc {
+func ClientAuthMiddleware(tokens *currentsession.TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !currentsession.GetCurrentSession().ValidateTokenHttpQuery(c) {
+		role, ok := tokens.AuthorizeHttpHeader(c)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
+		c.Set(roleKey, role)
 		c.Next()
 	}
 }
 
-func ClientAuthMiddleware() gin.HandlerFunc {
+// StudentOnlyMiddleware refuses observers. It goes after ClientAuthMiddleware.
+func StudentOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(roleKey) != currentsession.RoleStudent {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Observers can't change the station"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// trackActivity keeps the student's session from being released as idle. It
+// goes after the client auth middlewares. Watching the camera counts as long as
+// frames are still being delivered.
+func (s *Server) trackActivity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(roleKey) == currentsession.RoleStudent {
+			s.idle.Touch()
+			c.Writer = &activityWriter{ResponseWriter: c.Writer, idle: s.idle}
+		}
+		c.Next()
+	}
+}
+
+type activityWriter struct {
+	gin.ResponseWriter
+	idle *currentsession.IdleMonitor
+}
+
+func (w *activityWriter) Write(data []byte) (int, error) {
+	w.idle.Touch()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *activityWriter) WriteString(text string) (int, error) {
+	w.idle.Touch()
+	return w.ResponseWriter.WriteString(text)
+}
+
+func BackendAuthMiddleware(cfg *config.Live) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !currentsession.GetCurrentSession().ValidateTokenHttpHeader(c) {
+		if c.Request.Header.Get("Authorization") != cfg.Get().Auth.MasterServerAPISecret {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
@@ -219,116 +669,405 @@ func ClientAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-func BackendAuthMiddleware(cfg config.Config) gin.HandlerFunc {
+// ClientOrBackendAuthMiddleware lets through both the session client and the master server
+func ClientOrBackendAuthMiddleware(cfg *config.Live, tokens *currentsession.TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if c.Request.Header.Get("Authorization") != cfg.MASTER_SERVER_API_SECRET {
+		if c.Request.Header.Get("Authorization") == cfg.Get().Auth.MasterServerAPISecret {
+			c.Next()
+			return
+		}
+		role, ok := tokens.AuthorizeHttpHeader(c)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
+		c.Set(roleKey, role)
 		c.Next()
 	}
 }
 
-func (s *Server) diconnectWebSocket() {
+// handler describing what this station has, so clients can render controls from data
+func handleCapabilities(
+	server *Server,
+	registry *peripherals.Registry,
+	cam *peripherals.Peripheral[*camera.WebcamServer],
+	device *peripherals.Peripheral[*analogdiscovery.AnalogDiscoveryDevice],
+	pot *peripherals.Peripheral[*potentiometer.Potentiometer],
+	mux *peripherals.Peripheral[*multiplexer.MultiplexerModule],
+) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		deviceType, _ := server.board.Get()
+		res := gin.H{
+			"deviceType":      deviceType,
+			"peripherals":     registry.Statuses(),
+			"uart":            server.u.Info(),
+			"analogDiscovery": nil,
+			"camera":          nil,
+			"potentiometer":   nil,
+			"multiplexer":     nil,
+		}
+
+		if device, ok := device.Get(); ok {
+			capabilities, err := device.Capabilities()
+			if err != nil {
+				log.Printf("Error reading Analog Discovery capabilities: %v", err)
+			} else {
+				res["analogDiscovery"] = capabilities
+			}
+		}
+		if cam, ok := cam.Get(); ok {
+			res["camera"] = cam.Info()
+		}
+		if pot, ok := pot.Get(); ok {
+			res["potentiometer"] = gin.H{"address": pot.Address()}
+		}
+		if mux, ok := mux.Get(); ok {
+			res["multiplexer"] = gin.H{"count": mux.Count(), "channels": mux.ChannelCount()}
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// diconnectWebSocket ends the session and tells the clients why, as one of the
+// station reset reasons
+func (s *Server) diconnectWebSocket(reason string) {
 	message := WsMessage{
 		Type: "disconnect",
-	}
-	json, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("JSON marshal error: %v", err)
-		return
+		Text: reason,
 	}
 
 	s.wsConnMu.Lock()
 	defer s.wsConnMu.Unlock()
 
 	if s.wsConn != nil {
-		s.wsConn.WriteMessage(websocket.TextMessage, json)
-		s.wsConn.Close()
+		go s.closeWebSocket(s.wsConn, message)
 		s.wsConn = nil
 	}
+	// Observers belong to the session as well
+	for conn := range s.observers {
+		go s.closeWebSocket(conn, message)
+		delete(s.observers, conn)
+	}
 
 	// Immediately reset the session when forced disconnect occurs
 	currentsession.GetCurrentSession().Reset()
 }
 
 type WsMessage struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type string          `json:"type"`
+	Text string          `json:"text"`
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
-// for FPGA and MCU program files
-const (
-	maxUploadSize = 10 * (10 << 20) // 100 MB
-	uploadPath    = "./uploads"
-)
+// writeWebSocket queues message for conn in the protocol version it speaks
+func (s *Server) writeWebSocket(conn *websocket.Conn, message WsMessage) error {
+	json, err := s.encodeMessage(conn, message)
+	if err != nil {
+		return err
+	}
+	return s.queueWebSocket(conn, websocket.TextMessage, json)
+}
+
+// closeWebSocket sends message as the last frame of conn and closes it
+func (s *Server) closeWebSocket(conn *websocket.Conn, message WsMessage) {
+	defer s.unregisterClient(conn)
+	json, err := s.encodeMessage(conn, message)
+	if err != nil {
+		return
+	}
+	if client, ok := s.wsClients.Load(conn); ok {
+		if err := client.(*wsClient).write(websocket.TextMessage, json); err != nil {
+			log.Printf("WebSocket write error: %v", err)
+		}
+	}
+}
+
+// notifyWebSocket sends a message to the connected client and the observers
+func (s *Server) notifyWebSocket(message WsMessage) {
+	s.wsConnMu.Lock()
+	conns := slices.Collect(maps.Keys(s.observers))
+	if s.wsConn != nil {
+		conns = append(conns, s.wsConn)
+	}
+	s.wsConnMu.Unlock()
+
+	s.broadcast(conns, message)
+}
+
+// sendCountdown brings a client that just connected up to date with the remaining time
+func (s *Server) sendCountdown(conn *websocket.Conn) {
+	countdown := newCountdown(time.Now())
+	if err := s.writeWebSocket(conn, countdown.message("countdown", countdown.SessionEndTime.Format(time.RFC3339))); err != nil {
+		log.Printf("WebSocket write error: %v", err)
+	}
+}
+
+// notifyObservers sends a message to the observers only
+func (s *Server) notifyObservers(message WsMessage) {
+	s.broadcast(s.observerConns(), message)
+}
+
+func (s *Server) observerConns() []*websocket.Conn {
+	s.wsConnMu.Lock()
+	defer s.wsConnMu.Unlock()
+	return slices.Collect(maps.Keys(s.observers))
+}
+
+func (s *Server) broadcast(conns []*websocket.Conn, message WsMessage) {
+	for _, conn := range conns {
+		if err := s.writeWebSocket(conn, message); err != nil {
+			log.Printf("WebSocket write error: %v", err)
+		}
+	}
+}
+
+// handleObserverWebSocket streams a copy of the UART traffic and of the
+// student's changes to an observer
+func (s *Server) handleObserverWebSocket(w http.ResponseWriter, r *http.Request) {
+	framing, err := s.framingFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	conn, err := s.wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	// Observers only read, so their connection is kept alive by the pings
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	client := s.registerClient(conn, r, currentsession.RoleObserver, framing)
+	s.wsConnMu.Lock()
+	s.observers[conn] = struct{}{}
+	s.wsConnMu.Unlock()
+	fmt.Println("Observer connected")
+	session := currentsession.GetCurrentSession().ID()
+	s.auditWebSocket(session, currentsession.RoleObserver, "connect", nil, nil)
+	s.sendCountdown(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.wsConnMu.Lock()
+		delete(s.observers, conn)
+		s.wsConnMu.Unlock()
+		s.unregisterClient(conn)
+		fmt.Println("Observer disconnected")
+		s.auditWebSocket(session, currentsession.RoleObserver, "disconnect", nil, nil)
+	}()
+
+	seq, resume := uartSinceFromQuery(r)
+	go s.handleUARTToWS(client, ctx, seq, resume)
 
-func (s *Server) handleWSToUART(conn *websocket.Conn) {
+	// Observers can't write to the UART. Version 1 frames are dropped, version 2
+	// requests are refused.
 	for {
-		_, message, err := conn.ReadMessage()
+		messageType, frame, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if client.version == 2 && messageType == websocket.TextMessage {
+			s.handleRequest(conn, client, session, frame)
+		}
+	}
+}
+
+// mirrorStateChanges tells observers about every change the student makes,
+// with the request body when it is JSON
+func (s *Server) mirrorStateChanges() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body json.RawMessage
+		if c.Request.Body != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+			data, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(data))
+			if json.Valid(data) {
+				body = data
+			}
+		}
+
+		c.Next()
+
+		if c.Writer.Status() < http.StatusBadRequest {
+			s.notifyObservers(WsMessage{Type: "state", Text: c.Request.URL.Path, Data: body})
+		}
+	}
+}
+
+// auditWebSocket records what a WebSocket client did in the audit log of session
+func (s *Server) auditWebSocket(session, role, action string, params any, err error) {
+	entry := audit.Entry{
+		Time:     time.Now(),
+		Role:     role,
+		Endpoint: "WS " + action,
+		Result:   audit.ResultOK,
+	}
+	if params != nil {
+		entry.Params, _ = json.Marshal(params)
+	}
+	if err != nil {
+		entry.Result = audit.ResultError
+		entry.Error = err.Error()
+	}
+	if err := s.audit.Append(session, entry); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+	}
+}
+
+// handleWSToUART reads the student's frames. Binary frames go to the UART as
+// they are. Text frames go there in the client's UART mode in version 1, and are
+// requests in version 2.
+func (s *Server) handleWSToUART(conn *websocket.Conn, client *wsClient, session string) {
+	client.instruments = make(chan WsEnvelope, wsInstrumentQueue)
+	defer close(client.instruments)
+	go s.runInstruments(conn, session, client.instruments)
+
+	for {
+		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			log.Printf("WebSocket read error: %v", err)
 			return
 		}
 
+		s.idle.Touch()
+
+		if messageType == websocket.BinaryMessage {
+			if err := s.writeUARTBytes(session, message); err != nil {
+				s.writeWebSocket(conn, WsMessage{Type: "error", Text: err.Error()})
+			}
+			continue
+		}
+
+		if client.version == 2 {
+			s.handleRequest(conn, client, session, message)
+			continue
+		}
+
 		var wsMessage WsMessage
 		err = json.Unmarshal(message, &wsMessage)
 		if err != nil {
 			log.Printf("Incoming from WS JSON unmarshal error: %v", err)
+			s.writeWebSocket(conn, WsMessage{Type: "error", Text: "Invalid message: " + err.Error()})
+			continue
 		}
 
 		// Forward message to UART
-		if err := s.u.Write([]byte(wsMessage.Text + "\n")); err != nil {
-			log.Printf("UART write error: %v", err)
+		if err := s.writeUART(session, client, wsMessage.Text); err != nil {
+			s.writeWebSocket(conn, WsMessage{Type: "error", Text: err.Error()})
 		}
 	}
 }
-func (s *Server) handleUARTToWS(conn *websocket.Conn, ctx context.Context) {
+
+// pumpUART reads the UART for as long as the backend runs, so nothing the board
+// prints is lost while no client is connected. Every connection follows the
+// buffer in its own handleUARTToWS.
+func (s *Server) pumpUART(ctx context.Context) {
 	buffer := make([]byte, 1024)
 	logCounter := 50
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Println("Exit UART-to-WS loop because of context cancellation")
 			return
 		default:
-			n, err := s.u.Read(buffer)
-			if err != nil {
-				log.Printf("UART read error: %v", err)
-				return
-			}
-			// Better to sleep than to keep using CPU
-			if n == 0 {
-				logCounter--
-				if logCounter == 0 {
-					logCounter = 50
-					fmt.Println("No data from UART (Read 0 bytes)")
-				}
-				time.Sleep(100 * time.Millisecond)
-				continue
+		}
+
+		n, err := s.u.Read(buffer)
+		if err != nil {
+			log.Printf("UART read error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		// Better to sleep than to keep using CPU
+		if n == 0 {
+			logCounter--
+			if logCounter == 0 {
+				logCounter = 50
+				fmt.Println("No data from UART (Read 0 bytes)")
 			}
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
 
-			fmt.Println("Data from UART: ", string(buffer[:n]))
+		fmt.Println("Data from UART: ", string(buffer[:n]))
+		s.uartBuffer.Append(buffer[:n])
+	}
+}
 
-			message := WsMessage{
-				Type: "uart",
-				Text: string(buffer[:n]),
-			}
+// uartSinceFromQuery is where a connection starts following the UART output. A
+// reconnecting client passes the last seq it got, a new one gets the whole
+// session's output.
+func uartSinceFromQuery(r *http.Request) (seq uint64, resume bool) {
+	seq, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	return seq, err == nil
+}
 
-			json, err := json.Marshal(message)
+// uartFrame renders UART output in the client's mode: as text, as hex, or in
+// raw mode as a binary frame of the chunk's seq (8 bytes, big-endian) followed
+// by the exact bytes
+func (s *Server) uartFrame(client *wsClient, chunk uart.Chunk) (wsFrame, error) {
+	text := string(chunk.Data)
+	switch client.Framing().Mode {
+	case uart.ModeRaw:
+		frame := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(chunk.Data)), chunk.Seq)
+		return wsFrame{websocket.BinaryMessage, append(frame, chunk.Data...)}, nil
+	case uart.ModeHex:
+		text = hex.EncodeToString(chunk.Data)
+	}
+	data, _ := json.Marshal(gin.H{"seq": chunk.Seq})
+	message, err := s.encodeMessage(client.conn, WsMessage{Type: "uart", Text: text, Data: data})
+	return wsFrame{websocket.TextMessage, message}, err
+}
+
+// handleUARTToWS sends the buffered UART output after seq and then follows it.
+// With resume set, the client is told when part of what it missed was already dropped.
+// It writes to the connection itself, so it goes at the pace of the client.
+func (s *Server) handleUARTToWS(client *wsClient, ctx context.Context, seq uint64, resume bool) {
+	for {
+		chunks, dropped, changed := s.uartBuffer.Since(seq)
+		if dropped && resume {
+			data, _ := json.Marshal(gin.H{"after": seq})
+			gap, err := s.encodeMessage(client.conn, WsMessage{Type: "uart-gap", Data: data})
+			if err == nil {
+				err = client.write(websocket.TextMessage, gap)
+			}
 			if err != nil {
-				log.Printf("JSON marshal error: %v", err)
+				log.Printf("WebSocket write error: %v", err)
 				return
 			}
+		}
+		resume = true
 
-			// Forward UART data to WebSocket
-			if err := conn.WriteMessage(websocket.TextMessage, json); err != nil {
+		// Forward UART data to WebSocket
+		for _, chunk := range chunks {
+			frame, err := s.uartFrame(client, chunk)
+			if err == nil {
+				err = client.write(frame.messageType, frame.data)
+			}
+			if err != nil {
 				log.Printf("WebSocket write error: %v", err)
 				return
 			}
+			seq = chunk.Seq
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("Exit UART-to-WS loop because of context cancellation")
+			return
+		case <-changed:
 		}
 	}
 }
+
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Check if there's already an active connection
 	fmt.Println("Trying to lock wsConnMu")
@@ -347,6 +1086,13 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	fmt.Println("Checked that there is no active connection")
 
+	framing, err := s.framingFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.wsConnMu.Unlock()
+		return
+	}
+
 	// Upgrade the connection
 	conn, err := s.wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -357,24 +1103,32 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Upgraded WebSocket connection")
 
 	conn.SetReadDeadline(time.Now().Add(30 * time.Minute))
-	conn.SetWriteDeadline(time.Now().Add(30 * time.Minute))
 
 	// Store the connection
+	client := s.registerClient(conn, r, currentsession.RoleStudent, framing)
 	s.wsConn = conn
 	s.wsConnMu.Unlock()
 
+	// Entries go to the session the connection was opened in, even after it ended
+	session := currentsession.GetCurrentSession().ID()
+	s.auditWebSocket(session, currentsession.RoleStudent, "connect", nil, nil)
+	s.sendCountdown(conn)
+	s.events.Emit(webhook.WebSocketConnected, gin.H{"session": session})
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Clean up on disconnect - but don't reset session immediately
 	defer func() {
 		fmt.Println("WebSocket disconnected, beginning cleanup")
+		s.auditWebSocket(session, currentsession.RoleStudent, "disconnect", nil, nil)
+		s.events.Emit(webhook.WebSocketDropped, gin.H{"session": session})
 		s.wsConnMu.Lock()
 
-		// Cleanly close and clear the connection
-		if s.wsConn != nil {
-			s.wsConn.Close()
+		// Cleanly close and clear the connection, unless a forced disconnect did already
+		if s.wsConn == conn {
 			s.wsConn = nil
 		}
+		s.unregisterClient(conn)
 
 		// Cancel the context to stop the UART reading goroutine
 		cancel()
@@ -389,32 +1143,56 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("New WebSocket connection established")
 
 	// Start message handling
-	go s.handleUARTToWS(conn, ctx)
-	s.handleWSToUART(conn)
+	seq, resume := uartSinceFromQuery(r)
+	go s.handleUARTToWS(client, ctx, seq, resume)
+	s.handleWSToUART(conn, client, session)
+}
+
+func (s *Server) flasher(deviceType string) flasher.Flasher {
+	if deviceType == deviceFPGA {
+		return s.fpga
+	}
+	return s.mcu
+}
+
+// exampleFirmware is the known-good image for the board
+func (s *Server) exampleFirmware(deviceType string) string {
+	if deviceType == deviceFPGA {
+		return s.cfg.Get().FPGA.ExampleFirmware
+	}
+	return s.cfg.Get().MCU.ExampleFirmware
 }
 
-func flashFPGA(cfg config.Config, fp string) error {
-	fmt.Println("Flashing FPGA")
-	device := fpga.CreateFPGA(cfg.TDI, cfg.TDO, cfg.TCK, cfg.TMS)
-	err := device.Flash(fp)
-	return err
+func (s *Server) flashFirmware(deviceType string, fp string, progress flasher.ProgressFunc) error {
+	if deviceType == deviceMCU {
+		// st-flash resets the MCU, so release the UART until it is back up
+		s.u.Close()
+		defer s.u.Reset()
+	}
+	fmt.Println("Flashing", deviceType)
+	return s.flasher(deviceType).Flash(fp, progress)
 }
 
-func flashMCU(cfg config.Config, fp string, server *Server) error {
-	server.u.Close()
-	defer server.u.Reset()
-	fmt.Println("Flashing STM32")
-	err := stm32flash.Flash(fp)
-	return err
+// flashProgress tells the clients how far flashing got, once per percentage
+func (s *Server) flashProgress(deviceType string) flasher.ProgressFunc {
+	last := -1
+	return func(percent int) {
+		if percent == last {
+			return
+		}
+		last = percent
+		data, _ := json.Marshal(gin.H{"deviceType": deviceType, "percent": percent})
+		s.notifyWebSocket(WsMessage{Type: "flash-progress", Text: deviceType, Data: data})
+	}
 }
 
 // handler for programming FPGA and MCU
-func handleFirmware(cfg config.Config, isFPGA bool, server *Server) func(c *gin.Context) {
+func handleFirmware(server *Server, deviceType string) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		// Limit the size of the request body
-		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, server.cfg.Get().Server.MaxUploadSize)
 
-		if err := c.Request.ParseMultipartForm(maxUploadSize); err != nil {
+		if err := c.Request.ParseMultipartForm(server.cfg.Get().Server.MaxUploadSize); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "File too large"})
 			return
 		}
@@ -426,22 +1204,27 @@ func handleFirmware(cfg config.Config, isFPGA bool, server *Server) func(c *gin.
 			return
 		}
 
-		postfix := "MCU"
-		if isFPGA {
-			postfix = "FPGA"
-		}
-		fp := filepath.Join(uploadPath, postfix)
+		postfix := strings.ToUpper(deviceType)
+		fp := filepath.Join(server.cfg.Get().Server.UploadDir, postfix)
 
 		// Create the file on the server
 		c.SaveUploadedFile(file, fp)
 
 		fmt.Println("Firmware file uploaded:", file.Filename, " to ", fp, " for ", postfix)
 
-		if isFPGA {
-			err = flashFPGA(cfg, fp)
+		server.notifyWebSocket(WsMessage{Type: "flash-started", Text: deviceType})
+		err = server.flashFirmware(deviceType, fp, server.flashProgress(deviceType))
+		if err != nil {
+			server.notifyWebSocket(WsMessage{Type: "flash-failed", Text: err.Error()})
 		} else {
-			err = flashMCU(cfg, fp, server)
+			server.notifyWebSocket(WsMessage{Type: "flash-finished", Text: deviceType})
+		}
+
+		flashed := gin.H{"session": currentsession.GetCurrentSession().ID(), "deviceType": deviceType, "file": file.Filename, "ok": err == nil}
+		if err != nil {
+			flashed["error"] = err.Error()
 		}
+		server.events.Emit(webhook.FirmwareFlashed, flashed)
 
 		if err != nil {
 			fmt.Println("Error flashing device:", err)
@@ -453,8 +1236,9 @@ func handleFirmware(cfg config.Config, isFPGA bool, server *Server) func(c *gin.
 }
 
 func (s *Server) scheduleSessionReset() {
-	fmt.Println("Starting 6-second reconnection window")
-	s.timer.SetDuration(6 * time.Second)
+	reconnectWindow := s.cfg.Get().Session.ReconnectWindow
+	fmt.Println("Starting", reconnectWindow, "reconnection window")
+	s.timer.SetDuration(reconnectWindow)
 	s.timer.Start(func() {
 		fmt.Println("Reconnection window expired, resetting session")
 		s.wsConnMu.Lock()
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"digitrans-lab-go/internal/config"
+	currentsession "digitrans-lab-go/internal/current-session"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Countdown is the data of the countdown, expiry-warning and extended messages.
+// Clients should count down from Remaining rather than from their own clock.
+type Countdown struct {
+	SessionEndTime time.Time `json:"sessionEndTime"`
+	ServerTime     time.Time `json:"serverTime"`
+	Remaining      float64   `json:"remaining"` // seconds
+}
+
+func newCountdown(now time.Time) Countdown {
+	end := currentsession.GetCurrentSession().SessionEndTime()
+	return Countdown{SessionEndTime: end, ServerTime: now, Remaining: max(end.Sub(now), 0).Seconds()}
+}
+
+// message wraps the countdown in a WebSocket message of type
+func (c Countdown) message(messageType string, text string) WsMessage {
+	data, _ := json.Marshal(c)
+	return WsMessage{Type: messageType, Text: text, Data: data}
+}
+
+// sessionCountdown keeps the clients' idea of the remaining time in line with
+// the station's: it syncs the remaining time every session.countdown_interval
+// and warns once per session.expiry_warnings threshold
+type sessionCountdown struct {
+	cfg    *config.Live
+	notify func(WsMessage)
+
+	mu       sync.Mutex
+	warned   map[time.Duration]bool // thresholds already behind the current session
+	lastSync time.Time
+}
+
+func newSessionCountdown(cfg *config.Live, notify func(WsMessage)) *sessionCountdown {
+	return &sessionCountdown{cfg: cfg, notify: notify, warned: map[time.Duration]bool{}}
+}
+
+// Restart forgets the warnings sent so far. It runs when a session starts or
+// its end moves, so thresholds that are already behind aren't sent late.
+func (c *sessionCountdown) Restart() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	remaining := time.Until(currentsession.GetCurrentSession().SessionEndTime())
+	c.warned = map[time.Duration]bool{}
+	for _, threshold := range c.cfg.Get().Session.ExpiryWarnings {
+		c.warned[threshold] = remaining <= threshold
+	}
+}
+
+// Extended tells the clients about the new end of the session
+func (c *sessionCountdown) Extended() {
+	c.Restart()
+	c.notify(newCountdown(time.Now()).message("extended", currentsession.GetCurrentSession().SessionEndTime().Format(time.RFC3339)))
+}
+
+// Run sends the countdown and the warnings until ctx is done
+func (c *sessionCountdown) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.tick(now)
+		}
+	}
+}
+
+func (c *sessionCountdown) tick(now time.Time) {
+	if !currentsession.GetCurrentSession().IsActive() {
+		return
+	}
+	settings := c.cfg.Get().Session
+	countdown := newCountdown(now)
+	remaining := countdown.SessionEndTime.Sub(now)
+
+	c.mu.Lock()
+	// Only the smallest threshold crossed is sent when several are crossed at once
+	var warning time.Duration
+	for _, threshold := range settings.ExpiryWarnings {
+		if remaining <= threshold && !c.warned[threshold] {
+			c.warned[threshold] = true
+			if warning == 0 || threshold < warning {
+				warning = threshold
+			}
+		}
+	}
+	sync := settings.CountdownInterval > 0 && now.Sub(c.lastSync) >= settings.CountdownInterval
+	if sync {
+		c.lastSync = now
+	}
+	c.mu.Unlock()
+
+	if warning > 0 {
+		c.notify(countdown.message("expiry-warning", warning.String()))
+	}
+	if sync {
+		c.notify(countdown.message("countdown", countdown.SessionEndTime.Format(time.RFC3339)))
+	}
+}
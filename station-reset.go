@@ -0,0 +1,107 @@
+package main
+
+import (
+	"digitrans-lab-go/internal/config"
+	"digitrans-lab-go/internal/peripherals"
+	"digitrans-lab-go/internal/webhook"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Why a station reset ran
+const (
+	resetExpired     = "expired"
+	resetDeleted     = "deleted"
+	resetOverwritten = "overwritten"
+	resetCancelled   = "cancelled"
+	resetIdle        = "idle"
+)
+
+// ResetStep is the outcome of one step of a station reset
+type ResetStep struct {
+	Step  string `json:"step"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ResetReport is the outcome of a station reset
+type ResetReport struct {
+	Reason     string      `json:"reason"`
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt time.Time   `json:"finishedAt"`
+	Steps      []ResetStep `json:"steps"`
+}
+
+// stationReset returns the instruments to a known state after a session, so
+// the next student doesn't inherit running waveforms or latched outputs
+type stationReset struct {
+	mu  sync.Mutex // one reset at a time
+	cfg *config.Live
+	// steps by the name used in station_reset.steps
+	steps map[string]func() error
+	// events gets a report of every run, so the master server sees failed steps
+	events *webhook.Emitter
+
+	lastMu sync.Mutex
+	last   *ResetReport
+}
+
+// Run runs the configured steps in order. A failed step doesn't stop the others.
+func (r *stationReset) Run(reason string) ResetReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := ResetReport{Reason: reason, StartedAt: time.Now(), Steps: []ResetStep{}}
+	for _, name := range r.cfg.Get().StationReset.Steps {
+		step := ResetStep{Step: name, OK: true}
+		if err := r.steps[name](); err != nil {
+			step.OK = false
+			step.Error = err.Error()
+			log.Printf("Station reset (%s): %s failed: %v", reason, name, err)
+		} else {
+			log.Printf("Station reset (%s): %s done", reason, name)
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	report.FinishedAt = time.Now()
+
+	r.lastMu.Lock()
+	r.last = &report
+	r.lastMu.Unlock()
+	r.events.Emit(webhook.StationReset, report)
+	return report
+}
+
+func (r *stationReset) Last() *ResetReport {
+	r.lastMu.Lock()
+	defer r.lastMu.Unlock()
+	return r.last
+}
+
+// withPeripheral runs fn on the peripheral, failing when it is unavailable
+func withPeripheral[T any](p *peripherals.Peripheral[T], fn func(T) error) func() error {
+	return func() error {
+		value, ok := p.Get()
+		if !ok {
+			return fmt.Errorf("%s is unavailable", p.Name())
+		}
+		return fn(value)
+	}
+}
+
+// handler reporting the last station reset to the master server
+func handleGetStationReset(reset *stationReset) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		last := reset.Last()
+		if last == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No station reset has run yet"})
+			return
+		}
+		c.JSON(http.StatusOK, last)
+	}
+}
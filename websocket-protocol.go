@@ -0,0 +1,653 @@
+package main
+
+import (
+	analogdiscovery "digitrans-lab-go/internal/analog-discovery"
+	currentsession "digitrans-lab-go/internal/current-session"
+	"digitrans-lab-go/internal/multiplexer"
+	"digitrans-lab-go/internal/peripherals"
+	"digitrans-lab-go/internal/potentiometer"
+	"digitrans-lab-go/internal/uart"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+)
+
+// Version 2 of the WebSocket protocol is picked with this subprotocol. Without
+// it clients speak version 1, plain WsMessage frames whose text goes to the UART.
+const wsProtocolV2 = "digitrans-lab.v2"
+
+// Channels of protocol version 2
+const (
+	channelUART       = "uart"
+	channelSession    = "session"
+	channelInstrument = "instrument"
+	channelFlash      = "flash"
+	channelError      = "error"
+)
+
+// Error codes of protocol version 2
+const (
+	errorBadFrame           = "bad-frame"
+	errorUnsupportedVersion = "unsupported-version"
+	errorUnknownChannel     = "unknown-channel"
+	errorUnknownType        = "unknown-type"
+	errorForbidden          = "forbidden"
+	errorFailed             = "failed"
+	errorBusy               = "busy"
+)
+
+// WsEnvelope is a frame of protocol version 2. Requests carry an ID chosen by the
+// client, which the reply repeats in ReplyTo. Everything the server sends on its
+// own has an ID starting with "s".
+type WsEnvelope struct {
+	V       int             `json:"v"`
+	ID      string          `json:"id,omitempty"`
+	ReplyTo string          `json:"replyTo,omitempty"`
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"`
+	Text    string          `json:"text,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// WsError is the data of a frame on the error channel
+type WsError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Status  int    `json:"status,omitempty"`
+}
+
+// wsChannels says where the messages of version 1 go in version 2, as channel and type
+var wsChannels = map[string][2]string{
+	"uart":           {channelUART, "output"},
+	"uart-gap":       {channelUART, "gap"},
+	"uart-tx":        {channelUART, "tx"},
+	"countdown":      {channelSession, "countdown"},
+	"expiry-warning": {channelSession, "expiry-warning"},
+	"extended":       {channelSession, "extended"},
+	"idle-warning":   {channelSession, "idle-warning"},
+	"disconnect":     {channelSession, "ended"},
+	"state":          {channelInstrument, "changed"},
+	"flash-started":  {channelFlash, "started"},
+	"flash-progress": {channelFlash, "progress"},
+	"flash-finished": {channelFlash, "finished"},
+	"flash-failed":   {channelFlash, "failed"},
+	"error":          {channelError, "error"},
+}
+
+// wsOperations are the instrument requests of version 2. Each does what the REST
+// route at path does, with the same validation and response. Observers are told
+// about those that change the station, as they are about the route.
+var wsOperations = map[string]wsOperation{
+	"write-pin":              {"/api/write-pin", true, analogOperation(analogdiscovery.WritePin)},
+	"wavegen-channel":        {"/api/wavegen/write-channel", true, analogOperation(analogdiscovery.EnableWavegenChannel)},
+	"wavegen-function":       {"/api/wavegen/write-function", true, analogOperation(analogdiscovery.SetWavegenFunction)},
+	"wavegen-amplitude":      {"/api/wavegen/write-amplitude", true, analogOperation(analogdiscovery.SetWavegenAmplitude)},
+	"wavegen-frequency":      {"/api/wavegen/write-frequency", true, analogOperation(analogdiscovery.SetWavegenFrequency)},
+	"wavegen-duty-cycle":     {"/api/wavegen/write-duty-cycle", true, analogOperation(analogdiscovery.SetWavegenDutyCycle)},
+	"wavegen-config":         {"/api/wavegen/write-config", true, analogOperation(analogdiscovery.RunWavegen)},
+	"scope-data":             {"/api/scope/get-scope-data", true, analogOperation(analogdiscovery.GetScopeData)},
+	"logic-analyzer-capture": {"/api/logic-analyzer/capture", true, analogOperation((*analogdiscovery.AnalogDiscoveryDevice).CaptureLogicTransitions)},
+	"potentiometer-get":      {"/api/potentiometer/resistance", false, (*Server).getPotentiometer},
+	"potentiometer-set":      {"/api/potentiometer/resistance", true, (*Server).setPotentiometer},
+	"multiplexer-get":        {"/api/multiplexer", false, (*Server).getMultiplexer},
+	"multiplexer-select":     {"/api/multiplexer", true, (*Server).selectMultiplexer},
+	"mcu-reset":              {"/api/mcu/reset", true, (*Server).resetMCU},
+	"uart-speed":             {"/api/uart/speed", true, (*Server).setUARTSpeed},
+	"uart-config-get":        {"/api/uart/config", false, (*Server).getUARTConfig},
+	"uart-config-set":        {"/api/uart/config", true, (*Server).setUARTConfig},
+}
+
+// wsInstrumentQueue is how many instrument requests of a client may wait for
+// the one running
+const wsInstrumentQueue = 16
+
+const (
+	// wsSendQueue is how many messages may wait for a connection's writer. A
+	// client that lets it fill up has stopped reading and is disconnected.
+	wsSendQueue = 64
+	// wsWriteTimeout bounds every write, so a stuck client can't hold its writers
+	wsWriteTimeout = 10 * time.Second
+	// Connections are pinged every wsPingPeriod. Observers that don't answer
+	// within wsPongWait are dropped.
+	wsPingPeriod = 30 * time.Second
+	wsPongWait   = 60 * time.Second
+)
+
+var (
+	errWsClosed   = errors.New("the WebSocket connection is closed")
+	errWsOverflow = errors.New("the WebSocket client isn't reading, disconnected it")
+)
+
+type wsOperation struct {
+	path    string
+	changes bool // changes the station, so it is audited and mirrored to observers
+	run     func(s *Server, data json.RawMessage) (any, error)
+}
+
+// wsClient is what the server remembers about a WebSocket connection
+type wsClient struct {
+	version int
+	// token authenticates the instrument requests of the connection
+	token string
+	role  string
+
+	mu      sync.Mutex
+	framing uart.Framing
+
+	// instruments runs the student's instrument requests one after the other
+	instruments chan WsEnvelope
+
+	conn *websocket.Conn
+	// writeMu lets the connection's writer and its UART follower take turns
+	writeMu sync.Mutex
+	// send queues the frames for the writer, done is closed with the connection
+	send      chan wsFrame
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *wsClient) Framing() uart.Framing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.framing
+}
+
+func (c *wsClient) SetFraming(framing uart.Framing) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.framing = framing
+}
+
+// write sends a frame right away
+func (c *wsClient) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// wsFrame is a WebSocket message waiting for the connection's writer
+type wsFrame struct {
+	messageType int
+	data        []byte
+}
+
+// queue hands a frame to the connection's writer without waiting for it
+func (c *wsClient) queue(frame wsFrame) error {
+	select {
+	case <-c.done:
+		return errWsClosed
+	default:
+	}
+	select {
+	case c.send <- frame:
+		return nil
+	default:
+		c.close()
+		return errWsOverflow
+	}
+}
+
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// writeLoop writes the queued messages and the pings until the connection is closed
+func (c *wsClient) writeLoop() {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-c.done:
+			return
+		case frame := <-c.send:
+			err = c.write(frame.messageType, frame.data)
+		case <-ping.C:
+			err = c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		}
+		if err != nil {
+			log.Printf("WebSocket write error: %v", err)
+			c.close()
+			return
+		}
+	}
+}
+
+// framingFromQuery is the UART mode a client asks for with uart_mode and
+// line_ending, falling back to uart.mode and uart.line_ending
+func (s *Server) framingFromQuery(r *http.Request) (uart.Framing, error) {
+	cfg := s.cfg.Get()
+	framing := uart.Framing{Mode: cfg.UART.Mode, LineEnding: cfg.UART.LineEnding}
+	if mode := r.URL.Query().Get("uart_mode"); mode != "" {
+		framing.Mode = mode
+	}
+	if lineEnding := r.URL.Query().Get("line_ending"); lineEnding != "" {
+		framing.LineEnding = lineEnding
+	}
+	return framing, framing.Validate()
+}
+
+func (s *Server) registerClient(conn *websocket.Conn, r *http.Request, role string, framing uart.Framing) *wsClient {
+	client := &wsClient{
+		version: 1,
+		token:   r.URL.Query().Get("token"),
+		role:    role,
+		framing: framing,
+		conn:    conn,
+		send:    make(chan wsFrame, wsSendQueue),
+		done:    make(chan struct{}),
+	}
+	if conn.Subprotocol() == wsProtocolV2 {
+		client.version = 2
+	}
+	s.wsClients.Store(conn, client)
+	go client.writeLoop()
+	return client
+}
+
+// unregisterClient closes the connection and forgets it
+func (s *Server) unregisterClient(conn *websocket.Conn) {
+	if client, ok := s.wsClients.LoadAndDelete(conn); ok {
+		client.(*wsClient).close()
+	}
+	conn.Close()
+}
+
+func (s *Server) clientOf(conn *websocket.Conn) *wsClient {
+	if client, ok := s.wsClients.Load(conn); ok {
+		return client.(*wsClient)
+	}
+	return &wsClient{version: 1, framing: uart.Framing{Mode: uart.ModeLine, LineEnding: "lf"}}
+}
+
+// queueWebSocket hands a frame to the writer of conn
+func (s *Server) queueWebSocket(conn *websocket.Conn, messageType int, data []byte) error {
+	client, ok := s.wsClients.Load(conn)
+	if !ok {
+		return errWsClosed
+	}
+	return client.(*wsClient).queue(wsFrame{messageType, data})
+}
+
+// encodeMessage renders message in the protocol version conn speaks
+func (s *Server) encodeMessage(conn *websocket.Conn, message WsMessage) ([]byte, error) {
+	if s.clientOf(conn).version == 1 {
+		return json.Marshal(message)
+	}
+	channel, messageType := channelSession, message.Type
+	if mapped, ok := wsChannels[message.Type]; ok {
+		channel, messageType = mapped[0], mapped[1]
+	}
+	return json.Marshal(WsEnvelope{
+		V:       2,
+		ID:      s.nextMessageID(),
+		Channel: channel,
+		Type:    messageType,
+		Text:    message.Text,
+		Data:    message.Data,
+	})
+}
+
+func (s *Server) nextMessageID() string {
+	return "s" + strconv.FormatUint(s.wsMsgSeq.Add(1), 10)
+}
+
+func (s *Server) writeEnvelope(conn *websocket.Conn, envelope WsEnvelope) error {
+	envelope.V = 2
+	if envelope.ID == "" {
+		envelope.ID = s.nextMessageID()
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return s.queueWebSocket(conn, websocket.TextMessage, data)
+}
+
+// replyError answers a frame with an error. replyTo is empty when the frame
+// couldn't be read far enough to know its ID.
+func (s *Server) replyError(conn *websocket.Conn, replyTo string, wsError WsError) {
+	data, _ := json.Marshal(wsError)
+	if err := s.writeEnvelope(conn, WsEnvelope{ReplyTo: replyTo, Channel: channelError, Type: "error", Text: wsError.Message, Data: data}); err != nil {
+		log.Printf("WebSocket write error: %v", err)
+	}
+}
+
+func (s *Server) replyAck(conn *websocket.Conn, request WsEnvelope, data json.RawMessage) {
+	if err := s.writeEnvelope(conn, WsEnvelope{ReplyTo: request.ID, Channel: request.Channel, Type: "ack", Data: data}); err != nil {
+		log.Printf("WebSocket write error: %v", err)
+	}
+}
+
+// handleRequest answers one frame of a version 2 client
+func (s *Server) handleRequest(conn *websocket.Conn, client *wsClient, session string, frame []byte) {
+	var request WsEnvelope
+	if err := json.Unmarshal(frame, &request); err != nil {
+		s.replyError(conn, "", WsError{Code: errorBadFrame, Message: err.Error()})
+		return
+	}
+	if request.V != 2 {
+		s.replyError(conn, request.ID, WsError{Code: errorUnsupportedVersion, Message: fmt.Sprintf("version %d is not supported, use 2", request.V)})
+		return
+	}
+	if request.ID == "" {
+		s.replyError(conn, "", WsError{Code: errorBadFrame, Message: "id is required"})
+		return
+	}
+	if client.role != currentsession.RoleStudent {
+		s.replyError(conn, request.ID, WsError{Code: errorForbidden, Message: "Observers can't change the station"})
+		return
+	}
+
+	switch request.Channel {
+	case channelUART:
+		switch request.Type {
+		case "write":
+			var write struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(request.Data, &write); err != nil {
+				s.replyError(conn, request.ID, WsError{Code: errorBadFrame, Message: "data must be {\"text\": ...}"})
+				return
+			}
+			if err := s.writeUART(session, client, write.Text); err != nil {
+				s.replyError(conn, request.ID, WsError{Code: errorFailed, Message: err.Error()})
+				return
+			}
+			s.replyAck(conn, request, nil)
+		case "mode":
+			framing := client.Framing()
+			if err := json.Unmarshal(request.Data, &framing); err != nil {
+				s.replyError(conn, request.ID, WsError{Code: errorBadFrame, Message: "data must be {\"mode\": ..., \"lineEnding\": ...}"})
+				return
+			}
+			if err := framing.Validate(); err != nil {
+				s.replyError(conn, request.ID, WsError{Code: errorFailed, Message: err.Error()})
+				return
+			}
+			client.SetFraming(framing)
+			data, _ := json.Marshal(framing)
+			s.replyAck(conn, request, data)
+		default:
+			s.replyError(conn, request.ID, WsError{Code: errorUnknownType, Message: fmt.Sprintf("unknown uart request %q", request.Type)})
+		}
+
+	case channelSession:
+		if request.Type != "sync" {
+			s.replyError(conn, request.ID, WsError{Code: errorUnknownType, Message: fmt.Sprintf("unknown session request %q", request.Type)})
+			return
+		}
+		data, _ := json.Marshal(newCountdown(time.Now()))
+		s.replyAck(conn, request, data)
+
+	case channelInstrument:
+		if _, ok := wsOperations[request.Type]; !ok {
+			s.replyError(conn, request.ID, WsError{Code: errorUnknownType, Message: fmt.Sprintf("unknown instrument operation %q", request.Type)})
+			return
+		}
+		select {
+		case client.instruments <- request:
+		default:
+			s.replyError(conn, request.ID, WsError{Code: errorBusy, Message: "too many instrument requests are waiting"})
+		}
+
+	default:
+		s.replyError(conn, request.ID, WsError{Code: errorUnknownChannel, Message: fmt.Sprintf("unknown channel %q", request.Channel)})
+	}
+}
+
+// runInstruments answers the instrument requests of a student's connection in
+// the order they came. Some take seconds, e.g. a logic analyzer capture waiting
+// for its trigger, so they don't run in the read loop, which keeps UART input
+// and control frames flowing meanwhile.
+func (s *Server) runInstruments(conn *websocket.Conn, session string, requests <-chan WsEnvelope) {
+	for request := range requests {
+		s.runInstrument(conn, session, request)
+	}
+}
+
+func (s *Server) runInstrument(conn *websocket.Conn, session string, request WsEnvelope) {
+	// Requests still queued when the session ended mustn't touch the next one's station
+	if currentsession.GetCurrentSession().ID() != session {
+		s.replyError(conn, request.ID, WsError{Code: errorForbidden, Message: "The session has ended", Status: http.StatusUnauthorized})
+		return
+	}
+
+	operation := wsOperations[request.Type]
+	response, err := operation.run(s, request.Data)
+	if operation.changes {
+		var params any
+		if len(request.Data) > 0 {
+			params = request.Data
+		}
+		s.auditWebSocket(session, currentsession.RoleStudent, request.Type, params, err)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		var statusErr wsStatusError
+		if errors.As(err, &statusErr) {
+			status = statusErr.status
+		}
+		s.replyError(conn, request.ID, WsError{Code: errorFailed, Message: err.Error(), Status: status})
+		return
+	}
+
+	if operation.changes {
+		s.notifyObservers(WsMessage{Type: "state", Text: operation.path, Data: request.Data})
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.replyError(conn, request.ID, WsError{Code: errorFailed, Message: err.Error(), Status: http.StatusInternalServerError})
+		return
+	}
+	s.replyAck(conn, request, data)
+}
+
+// wsStatusError is an instrument request that failed with the HTTP status its
+// REST route answers
+type wsStatusError struct {
+	status int
+	err    error
+}
+
+func (e wsStatusError) Error() string {
+	return e.err.Error()
+}
+
+func (e wsStatusError) Unwrap() error {
+	return e.err
+}
+
+// decodeOperation reads the data of an instrument request as its REST route
+// reads the body
+func decodeOperation(data json.RawMessage, request any) error {
+	if err := json.Unmarshal(data, request); err != nil {
+		return wsStatusError{http.StatusBadRequest, errors.New("Invalid request body")}
+	}
+	if err := binding.Validator.ValidateStruct(request); err != nil {
+		return wsStatusError{http.StatusBadRequest, err}
+	}
+	return nil
+}
+
+// instrument is the open peripheral p, or the error its routes answer with while it is missing
+func instrument[T any](p *peripherals.Peripheral[T]) (T, error) {
+	value, ok := p.Get()
+	if !ok {
+		return value, wsStatusError{http.StatusServiceUnavailable, fmt.Errorf("%s is unavailable", p.Status().Name)}
+	}
+	return value, nil
+}
+
+// analogOperation runs call on the Analog Discovery with the request's data
+func analogOperation[R, T any](call func(*analogdiscovery.AnalogDiscoveryDevice, R) (T, error)) func(*Server, json.RawMessage) (any, error) {
+	return func(s *Server, data json.RawMessage) (any, error) {
+		device, err := instrument(s.device)
+		if err != nil {
+			return nil, err
+		}
+		var request R
+		if err := decodeOperation(data, &request); err != nil {
+			return nil, err
+		}
+		response, err := call(device, request)
+		if err != nil {
+			return nil, wsStatusError{analogdiscovery.ErrorStatus(err), err}
+		}
+		return response, nil
+	}
+}
+
+func (s *Server) getPotentiometer(json.RawMessage) (any, error) {
+	pot, err := instrument(s.pot)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"percentage": pot.GetResistancePercentage()}, nil
+}
+
+func (s *Server) setPotentiometer(data json.RawMessage) (any, error) {
+	pot, err := instrument(s.pot)
+	if err != nil {
+		return nil, err
+	}
+	var request potentiometer.PostPotentiometerSetResistanceRequest
+	if err := decodeOperation(data, &request); err != nil {
+		return nil, err
+	}
+	percentage, err := pot.SetResistancePercentage(request.Percentage)
+	if err != nil {
+		return nil, wsStatusError{http.StatusBadRequest, fmt.Errorf("failed to set resistance: %w", err)}
+	}
+	return gin.H{"percentage": percentage}, nil
+}
+
+func (s *Server) getMultiplexer(json.RawMessage) (any, error) {
+	mux, err := instrument(s.mux)
+	if err != nil {
+		return nil, err
+	}
+	channel1, err := mux.InputChannel(1)
+	if err != nil {
+		return nil, err
+	}
+	channel2, err := mux.InputChannel(2)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{"channel1": channel1, "channel2": channel2}, nil
+}
+
+func (s *Server) selectMultiplexer(data json.RawMessage) (any, error) {
+	mux, err := instrument(s.mux)
+	if err != nil {
+		return nil, err
+	}
+	var request multiplexer.SelectInputChannelRequest
+	if err := decodeOperation(data, &request); err != nil {
+		return nil, err
+	}
+	if err := mux.SelectInputChannel(request.Multiplexer, request.Channel); err != nil {
+		return nil, err
+	}
+	return gin.H{"message": "Input channel selected"}, nil
+}
+
+func (s *Server) resetMCU(json.RawMessage) (any, error) {
+	if _, err := instrument(s.board); err != nil {
+		return nil, err
+	}
+	if err := s.mcu.Reset(); err != nil {
+		log.Printf("Error resetting STM32: %v", err)
+		return nil, wsStatusError{http.StatusBadRequest, err}
+	}
+	return gin.H{"message": "STM32 has been reset"}, nil
+}
+
+func (s *Server) setUARTSpeed(data json.RawMessage) (any, error) {
+	var request uart.PostUartChangeSpeedRequest
+	if err := decodeOperation(data, &request); err != nil {
+		return nil, err
+	}
+	if err := s.u.ChangeSpeed(request.Speed); err != nil {
+		return nil, wsStatusError{http.StatusBadRequest, fmt.Errorf("failed to change speed: %w", err)}
+	}
+	return gin.H{"speed": request.Speed}, nil
+}
+
+func (s *Server) getUARTConfig(json.RawMessage) (any, error) {
+	return s.u.Settings(), nil
+}
+
+// setUARTConfig reopens the port like PUT /api/uart/config, fields left out keep their value
+func (s *Server) setUARTConfig(data json.RawMessage) (any, error) {
+	settings := s.u.Settings()
+	if err := decodeOperation(data, &settings); err != nil {
+		return nil, err
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, wsStatusError{http.StatusBadRequest, err}
+	}
+	if err := s.u.Configure(settings); err != nil {
+		if errors.Is(err, uart.ErrNotOpen) {
+			return nil, wsStatusError{http.StatusConflict, err}
+		}
+		return nil, fmt.Errorf("failed to configure the UART: %w", err)
+	}
+	return s.u.Settings(), nil
+}
+
+// writeUART sends text typed by the student to the board, in the client's UART mode
+func (s *Server) writeUART(session string, client *wsClient, text string) error {
+	data, err := client.Framing().Encode(text)
+	if err != nil {
+		return err
+	}
+	return s.writeUARTBytes(session, data)
+}
+
+// writeUARTBytes sends exactly data to the board
+func (s *Server) writeUARTBytes(session string, data []byte) error {
+	err := s.u.Write(data)
+	params := gin.H{"hex": hex.EncodeToString(data)}
+	if utf8.Valid(data) {
+		params = gin.H{"text": string(data)}
+	}
+	s.auditWebSocket(session, currentsession.RoleStudent, "uart-write", params, err)
+	if err != nil {
+		log.Printf("UART write error: %v", err)
+		return errors.New("UART write failed")
+	}
+	for _, conn := range s.observerConns() {
+		if err := s.writeWebSocket(conn, uartTxMessage(s.clientOf(conn).Framing(), data)); err != nil {
+			log.Printf("WebSocket write error: %v", err)
+		}
+	}
+	return nil
+}
+
+// uartTxMessage shows an observer what the student sent: as text in line mode,
+// and as hex with data {"encoding": "hex"} in raw and hex mode or when the bytes
+// aren't text
+func uartTxMessage(framing uart.Framing, data []byte) WsMessage {
+	if framing.Mode == uart.ModeLine && utf8.Valid(data) {
+		return WsMessage{Type: "uart-tx", Text: string(data)}
+	}
+	encoding, _ := json.Marshal(gin.H{"encoding": "hex"})
+	return WsMessage{Type: "uart-tx", Text: hex.EncodeToString(data), Data: encoding}
+}